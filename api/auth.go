@@ -0,0 +1,58 @@
+package api
+
+import "net/http"
+
+// Principal is the authenticated caller.
+type Principal struct {
+	Subject string
+	// Scheme is the kind of the credentials like basic, bearer or jwt.
+	Scheme string
+	Roles  []string
+	Scopes []string
+	// Claims are the claims of a jwt.
+	Claims map[string]interface{}
+}
+
+// Authenticator verifies the credentials of a call. The header is the http
+// header of json-rpc and raw http, the handshake header of websocket, or the
+// metadata of grpc. It returns nil and no error if there are no credentials
+// of its kind, so that the next Authenticator is tried.
+type Authenticator interface {
+	Authenticate(header http.Header) (*Principal, error)
+}
+
+// AuthOption authenticates the calls of json-rpc, raw http, raw websocket and grpc.
+// Invalid credentials are refused at once, and a call without credentials is
+// refused unless its method is public.
+type AuthOption struct {
+	// Authenticators are tried in order until one recognizes the credentials.
+	Authenticators []Authenticator
+	// Public are the patterns of CallInfo.Method in the syntax of path.Match,
+	// which may be called without credentials, e.g. rpc.Version or /pkg.Service/*.
+	// grpc.health.v1.Health is always public.
+	Public []string
+	// Policies authorize the authenticated callers of the methods which are not public.
+	// They may be loaded from a file by auth.LoadPolicies.
+	Policies []Policy
+}
+
+// Policy is a rule of the methods matching Pattern in the syntax of path.Match,
+// e.g. admin.* or /pkg.Service/*. A caller must have one of Roles if it is not
+// empty, and all of Scopes. Every policy matching a method must be satisfied.
+type Policy struct {
+	Pattern string
+	Roles   []string
+	Scopes  []string
+}
+
+const PrincipalKey = "_PrincipalKey_"
+
+// PrincipalOf returns the authenticated caller of ctx, or nil.
+func PrincipalOf(ctx Context) *Principal {
+	v, ok := ctx.Value(PrincipalKey)
+	if !ok {
+		return nil
+	}
+	p, _ := v.(*Principal)
+	return p
+}
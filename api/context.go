@@ -1,10 +1,24 @@
 package api
 
+import (
+	"context"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/url"
+)
+
 type Context interface {
 	CtxID() interface{}
 	ClientIP() string
+	// PeerIdentity returns the identity of a client which presented a verified
+	// certificate, or nil if there is none.
+	PeerIdentity() *PeerIdentity
 	WithValue(key string, value any)
 	Value(key string) (any, bool)
+	// Context is done once the client goes away or the timeout of the
+	// json-rpc method expires, and carries the deadline of the call.
+	Context() context.Context
 }
 
 type RawHttpContext interface {
@@ -13,3 +27,26 @@ type RawHttpContext interface {
 	Query(key string) string
 	WriteData(code int, contType string, data []byte) error
 }
+
+// RawHttpBodyContext is the RawHttpContext passed to the handle of a route with
+// api.WithStreamBody, which finds it by a type assertion. It is kept apart from
+// RawHttpContext, so that the implementations of that one still satisfy it.
+type RawHttpBodyContext interface {
+	RawHttpContext
+	// Body is the request body bounded by the max body size.
+	Body() io.Reader
+}
+
+// PeerIdentity is taken from the leaf of the verified client certificate chain.
+type PeerIdentity struct {
+	Subject        string
+	CommonName     string
+	DNSNames       []string
+	EmailAddresses []string
+	IPAddresses    []net.IP
+	URIs           []*url.URL
+	// SpiffeID is the first URI SAN with the spiffe scheme, e.g. spiffe://example.org/ns/default/sa/api
+	SpiffeID string
+
+	Certificate *x509.Certificate
+}
@@ -11,6 +11,16 @@ const (
 	ReserveMinError = -32099
 	ReserveMaxError = -32000
 	// ReserveMaxError end for json-rpc 2.0
+
+	// Unauthenticated begin for the errors of the server
+	Unauthenticated  = -32001
+	PermissionDenied = -32002
+	// ResourceExhausted has the data {"retry_after": seconds}.
+	ResourceExhausted = -32003
+	// Unavailable is returned when a call is shed by the concurrency limits.
+	Unavailable = -32004
+	// DeadlineExceeded is returned when the timeout of a json-rpc method expires.
+	DeadlineExceeded = -32005
 )
 
 var SysCodeMap = map[int]string{
@@ -19,4 +29,10 @@ var SysCodeMap = map[int]string{
 	MethodNotFound: "Method not found",
 	InvalidParams:  "Invalid params",
 	InternalError:  "Internal error",
+
+	Unauthenticated:   "Unauthenticated",
+	PermissionDenied:  "Permission denied",
+	ResourceExhausted: "Resource exhausted",
+	Unavailable:       "Service unavailable",
+	DeadlineExceeded:  "Deadline exceeded",
 }
@@ -3,6 +3,35 @@ package api
 // RawHttpHandle is a raw interface for creating api based http
 type RawHttpHandle func(RawHttpContext, []byte)
 
+// RawPathOption configures a route of RegisterPath.
+type RawPathOption func(*RawPath)
+
+// RawPath is the config of a route of RegisterPath.
+type RawPath struct {
+	// MaxBodySize overrides ServerOption.MaxBodySize.
+	MaxBodySize int64
+	// StreamBody passes a nil body to the handle, which reads it from RawHttpBodyContext.Body.
+	StreamBody bool
+}
+
+// WithMaxBodySize bounds the body of the route, larger ones are refused by 413.
+// It only applies to POST, like WithStreamBody.
+func WithMaxBodySize(n int64) RawPathOption {
+	return func(p *RawPath) {
+		p.MaxBodySize = n
+	}
+}
+
+// WithStreamBody lets the handle read the body as it arrives rather than
+// buffering it in memory, e.g. for uploads. The handle gets the body by
+// ctx.(api.RawHttpBodyContext).Body(), whose reads fail by *http.MaxBytesError
+// over the max body size.
+func WithStreamBody() RawPathOption {
+	return func(p *RawPath) {
+		p.StreamBody = true
+	}
+}
+
 // RawWsHandle is a raw interface for creating api based ws
 type RawWsHandle func(Context, WSMessage) error
 
@@ -0,0 +1,19 @@
+package api
+
+import "context"
+
+// HealthChecker reports whether a dependency of the server is healthy,
+// e.g. a database ping or a cache warm-up. A nil error means healthy.
+type HealthChecker func(ctx context.Context) error
+
+const (
+	HealthServing    = "SERVING"
+	HealthNotServing = "NOT_SERVING"
+)
+
+// HealthResult is the body of the readiness endpoint.
+type HealthResult struct {
+	Status string `json:"status"`
+	// Checks maps the name of every checker to "ok" or its error
+	Checks map[string]string `json:"checks,omitempty"`
+}
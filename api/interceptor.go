@@ -0,0 +1,36 @@
+package api
+
+// Transport is the protocol a call comes from.
+type Transport string
+
+const (
+	TransportHttp    Transport = "http"     // json-rpc over http
+	TransportWs      Transport = "ws"       // json-rpc over websocket
+	TransportRawHttp Transport = "raw_http" // routes of RegisterPath
+	TransportRawWs   Transport = "raw_ws"   // messages of RegisterRawWs
+	TransportGrpc    Transport = "grpc"
+)
+
+// CallInfo describes a call to the interceptors.
+type CallInfo struct {
+	Transport Transport
+	// Method is the json-rpc method like rpc.Add, the path of a raw http
+	// route, the path of raw websocket, or the full grpc method like
+	// /pkg.Service/Method.
+	Method string
+	// Request is the decoded params of json-rpc, the body of raw http,
+	// the WSMessage of raw websocket, or the grpc request message.
+	// It is nil for grpc streams.
+	Request interface{}
+}
+
+// Invoker calls the next interceptor, or the handler at the end of the chain.
+// The response is the json-rpc result or the grpc response message, and is
+// nil for raw http, raw websocket and grpc streams.
+type Invoker func(ctx Context) (interface{}, error)
+
+// Interceptor sees every call of every transport in the same way. It either
+// returns what invoke returns, or short-circuits the call with its own error.
+// A *JsonRpcError or a grpc status error is mapped to the error of each
+// transport, other errors are internal errors.
+type Interceptor func(ctx Context, info *CallInfo, invoke Invoker) (interface{}, error)
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"reflect"
+	"time"
+)
+
+// MethodDesc describes a json-rpc method to the middlewares.
+type MethodDesc struct {
+	Service string
+	Method  string
+	// ArgType and ReplyType are the types of the params and the reply
+	// of the method, args passed to the next handler must be of ArgType.
+	ArgType   reflect.Type
+	ReplyType reflect.Type
+}
+
+// JsonRpcHandler calls the next middleware, or the method at the end.
+type JsonRpcHandler func(ctx Context, args interface{}) (interface{}, *JsonRpcError)
+
+// JsonRpcMiddleware wraps the methods it is attached to. It may return an
+// error without calling next, pass other args to next, or change the reply.
+type JsonRpcMiddleware func(ctx Context, desc *MethodDesc, args interface{}, next JsonRpcHandler) (interface{}, *JsonRpcError)
+
+// JsonRpcServiceOption configures a service of RegisterJsonRPC.
+type JsonRpcServiceOption func(*JsonRpcService)
+
+// JsonRpcService is the config of a json-rpc service.
+type JsonRpcService struct {
+	Middlewares []JsonRpcMiddlewareRule
+	// Concurrency is the bulkhead of the service shared by its methods,
+	// so that a slow service can not take all the calls of the server.
+	Concurrency *ConcurrencyLimit
+	Timeouts    []JsonRpcTimeoutRule
+}
+
+// JsonRpcTimeoutRule sets the timeout of the methods matching Pattern like
+// JsonRpcMiddlewareRule. A zero Timeout means no timeout.
+type JsonRpcTimeoutRule struct {
+	Pattern string
+	Timeout time.Duration
+}
+
+// JsonRpcMiddlewareRule attaches Middleware to the methods whose full name
+// like rpc.Add matches Pattern in the syntax of path.Match, e.g. rpc.* or rpc.Add.
+type JsonRpcMiddlewareRule struct {
+	Pattern    string
+	Middleware JsonRpcMiddleware
+}
+
+// WithJsonRpcMiddleware attaches mw to the methods matching pattern.
+// Middlewares run in the order they are attached, inside the Interceptors.
+func WithJsonRpcMiddleware(pattern string, mw JsonRpcMiddleware) JsonRpcServiceOption {
+	return func(s *JsonRpcService) {
+		s.Middlewares = append(s.Middlewares, JsonRpcMiddlewareRule{Pattern: pattern, Middleware: mw})
+	}
+}
+
+// WithJsonRpcConcurrency limits the calls of the service in flight.
+// It applies outside the middlewares, and its bulkhead is service:<name>
+// in the metrics.
+func WithJsonRpcConcurrency(limit ConcurrencyLimit) JsonRpcServiceOption {
+	return func(s *JsonRpcService) {
+		s.Concurrency = &limit
+	}
+}
+
+// WithJsonRpcTimeout sets the timeout of the methods matching pattern, e.g. rpc.*
+// for the whole service or rpc.Add for a method. The last matching one applies,
+// and it overrides JsonRpcOption.Timeout. The caller gets a DeadlineExceeded
+// error once it expires, while the method sees it by api.Context.Context().
+func WithJsonRpcTimeout(pattern string, timeout time.Duration) JsonRpcServiceOption {
+	return func(s *JsonRpcService) {
+		s.Timeouts = append(s.Timeouts, JsonRpcTimeoutRule{Pattern: pattern, Timeout: timeout})
+	}
+}
@@ -1,26 +1,228 @@
 package api
 
 import (
+	"crypto/tls"
 	"encoding/base64"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/BabySid/gobase/log"
 	"github.com/BabySid/gorpc/codec"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/stats"
 )
 
 type JsonRpcOption struct {
 	Codec codec.CodecType
+	// Timeout bounds every json-rpc method unless api.WithJsonRpcTimeout
+	// sets its own, no timeout by default.
+	Timeout time.Duration
+	// MaxBodySize overrides ServerOption.MaxBodySize for json-rpc over http
+	// if it is not zero, a negative one is unlimited.
+	MaxBodySize int64
 }
+
+// GrpcOption tunes the grpc server. Zero values keep the defaults of grpc.
+type GrpcOption struct {
+	// Interceptors are chained in order, the first one is the outermost.
+	UnaryInterceptors  []grpc.UnaryServerInterceptor
+	StreamInterceptors []grpc.StreamServerInterceptor
+
+	// MaxRecvMsgSize is 4MB and MaxSendMsgSize is unlimited by default.
+	MaxRecvMsgSize int
+	MaxSendMsgSize int
+
+	// KeepaliveParams pings idle clients and ages connections out.
+	// KeepaliveEnforcement rejects clients pinging too often.
+	KeepaliveParams      *keepalive.ServerParameters
+	KeepaliveEnforcement *keepalive.EnforcementPolicy
+
+	// ConnectionTimeout bounds the http2 handshake of a new connection, 120s by default.
+	ConnectionTimeout time.Duration
+
+	MaxConcurrentStreams uint32
+
+	StatsHandlers []stats.Handler
+
+	// ServerOptions are passed to grpc.NewServer after the fields above.
+	ServerOptions []grpc.ServerOption
+}
+
+// GrpcWebOption serves the grpc services to browsers by grpc-web on the http
+// routes of the same port, both application/grpc-web and application/grpc-web-text.
+type GrpcWebOption struct {
+	// AllowedOrigins are the origins allowed by cors like CorsOption.AllowedOrigins,
+	// which replace those of ServerOption.Cors for grpc-web. ServerOption.Cors
+	// applies if it is empty, and cross origin calls are refused if both are empty.
+	AllowedOrigins []string
+}
+
+// RateLimit is a token bucket refilled by Rate tokens per second and holding
+// at most Burst tokens, which is max(1, Rate) if it is not positive.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// MethodRateLimit limits the methods matching Pattern in the syntax of path.Match
+// on CallInfo.Method, e.g. rpc.Add, admin.* or /upload. They share one bucket.
+type MethodRateLimit struct {
+	Pattern string
+	RateLimit
+}
+
+// RateLimitOption limits the calls of json-rpc, raw http, raw websocket and grpc.
+// A call must pass the limits of its caller, its method and the server in order.
+type RateLimitOption struct {
+	Global *RateLimit
+	// Methods are tried in order and the first matching one applies.
+	Methods []MethodRateLimit
+	// Caller limits each caller, which is the principal of Auth or the client ip.
+	Caller *RateLimit
+}
+
+// ConcurrencyLimit admits at most MaxInFlight calls at once, or any number of
+// calls if it is not positive, e.g. left zero in a config. Up to MaxQueue calls
+// wait for a slot in order, at most QueueTimeout if it is positive, and the
+// calls beyond the queue are shed at once.
+type ConcurrencyLimit struct {
+	MaxInFlight  int
+	MaxQueue     int
+	QueueTimeout time.Duration
+	// Adaptive moves the limit between Adaptive.MinInFlight and MaxInFlight by the latency.
+	Adaptive *AdaptiveLimit
+}
+
+// AdaptiveLimit cuts the in-flight limit by a tenth once the average latency of
+// a window of calls is above TargetLatency, and raises it by one otherwise.
+// A window is as many calls as the current limit.
+type AdaptiveLimit struct {
+	TargetLatency time.Duration
+	// MinInFlight is 1 by default.
+	MinInFlight int
+}
+
+// CorsOption lets the browser apps of other origins call the http routes like
+// json-rpc and RegisterPath, and open the websockets.
+type CorsOption struct {
+	// AllowedOrigins are like https://example.com, or patterns in the syntax of
+	// path.Match like https://*.example.com. "*" allows any other origin, but
+	// without the credentials, and not to open a websocket.
+	AllowedOrigins []string
+	// AllowedMethods are GET, POST and HEAD by default.
+	AllowedMethods []string
+	// AllowedHeaders are the headers the requests may have. Any requested
+	// header is allowed if it is empty.
+	AllowedHeaders []string
+	// ExposedHeaders are the headers of the responses readable by the apps.
+	ExposedHeaders []string
+	// AllowCredentials lets the origins allowed by name send the cookies and
+	// read the responses.
+	AllowCredentials bool
+	// MaxAge is how long the browsers cache a preflight.
+	MaxAge time.Duration
+}
+
+// TLSOption enables tls on the server listener. Tls is terminated before the
+// protocols are split, so http, websocket and grpc keep sharing one port.
+type TLSOption struct {
+	// CertFile and KeyFile are PEM encoded files. They are reloaded when
+	// either file changes.
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile enables mutual tls. Client certificates are verified against
+	// the CAs in this PEM file. If ClientAuth is tls.NoClientCert, it defaults to
+	// tls.VerifyClientCertIfGiven.
+	ClientCAFile string
+	ClientAuth   tls.ClientAuthType
+
+	// Config is the base config. It must provide the certificates itself
+	// if CertFile and KeyFile are empty.
+	Config *tls.Config
+}
+
 type ServerOption struct {
-	Addr        string
+	Addr string
+	// UnixAddrs are extra listeners like unix:///var/run/app.sock sharing the
+	// protocols of Addr. Addr may be empty to only serve unix sockets.
+	// Tls applies to Addr only.
+	UnixAddrs []string
+	// UnixSockMode is the permission of the socket files, 0660 by default.
+	UnixSockMode os.FileMode
+
 	ClusterName string
 
+	// MaxBodySize bounds the bodies of json-rpc over http and RegisterPath,
+	// larger ones are refused by 413. It is unlimited if it is not positive.
+	// JsonRpcOption.MaxBodySize and api.WithMaxBodySize override it.
+	MaxBodySize int64
+	// MaxWsMessageSize bounds the messages read by the websockets, 10MB by default.
+	MaxWsMessageSize int64
+
 	Logger log.Logger
 
 	JsonRpcOpt *JsonRpcOption
 
-	BeforeRun          func() error
+	// Interceptors wrap the calls of json-rpc, raw http, raw websocket and grpc.
+	// The first one is the outermost.
+	Interceptors []Interceptor
+
+	// RateLimitOpt rejects the calls over the limits before Auth checks the policies.
+	// The credentials are verified earlier by each transport, so the calls with
+	// invalid ones are refused by Unauthenticated without taking any token.
+	RateLimitOpt *RateLimitOption
+	// Auth authenticates the calls before the Interceptors.
+	Auth *AuthOption
+	// Concurrency limits the calls of the server after Auth. The json-rpc services
+	// may have their own limits by api.WithJsonRpcConcurrency. The grpc streams
+	// are not limited, since they may last as long as their connections.
+	Concurrency *ConcurrencyLimit
+
+	GrpcOpt *GrpcOption
+	// EnableReflection registers grpc.reflection.v1alpha for tools like grpcurl.
+	EnableReflection bool
+	GrpcWebOpt       *GrpcWebOption
+	// Cors applies to the http routes, to grpc-web unless GrpcWebOption has
+	// its own origins, and to the origin of the websocket handshakes. Only the
+	// same origin is allowed to open a websocket if it is nil.
+	Cors *CorsOption
+	// EnableChannelz registers grpc.channelz.v1, and links a summary page
+	// from the index of the inner services.
+	EnableChannelz bool
+
+	TLS *TLSOption
+	// EnableH2C serves http2 with prior knowledge on the cleartext listeners,
+	// for the http routes besides grpc. Http2 is always served over tls.
+	EnableH2C bool
+
+	// RuntimeDir is where the pid and net files are written, the working directory by default.
+	// PidFile and NetFile are their names, <binary>.pid and <binary>.net by default.
+	// <PidFile>.lock is locked while the server runs, so a second copy refuses to start.
+	RuntimeDir string
+	PidFile    string
+	NetFile    string
+
+	BeforeRun func() error
+	// AfterStop is called once the server has stopped if BeforeRun succeeded,
+	// so that the resources acquired by BeforeRun are released in order.
+	AfterStop func() error
+	// Reload is called by Server.RunUntilSignal on SIGHUP.
+	Reload func() error
+	// ShutdownTimeout bounds the graceful stop of Server.RunUntilSignal, and how long
+	// Server.Stop waits for the cancelled requests, 30s by default.
+	ShutdownTimeout time.Duration
+	// DrainDelay is how long Server.Shutdown keeps accepting after the readiness
+	// turns NOT_SERVING, so that the load balancers stop routing to the server
+	// before its listeners are closed. It is part of the shutdown timeout.
+	DrainDelay time.Duration
+
 	EnableInnerService bool
+	// AdminAddr moves the inner services to a separate listener, e.g. 127.0.0.1:9090,
+	// so that the public listener only exposes the business routes.
+	AdminAddr string
 }
 
 const (
@@ -28,8 +230,13 @@ const (
 	BuiltInPathWsJsonRPC = "_jsonrpc_ws_"
 	BuiltInPathRawWS     = "_raw_ws_"
 
-	BuiltInPathDIR     = "_dir_"
-	BuiltInPathMetrics = "_metrics_"
+	BuiltInPathDIR      = "_dir_"
+	BuiltInPathMetrics  = "_metrics_"
+	BuiltInPathChannelz = "_channelz_"
+
+	BuiltInPathHealth      = "_health_"
+	BuiltInPathHealthLive  = BuiltInPathHealth + "/live"
+	BuiltInPathHealthReady = BuiltInPathHealth + "/ready"
 )
 
 type BasicAuth struct {
@@ -45,6 +252,12 @@ func (ba BasicAuth) SetAuthHeader(head http.Header) {
 type ClientOption struct {
 	JsonRpcOpt *JsonRpcOption
 
+	// TLSConfig is used by the https, wss and grpcs schemes
+	TLSConfig *tls.Config
+	// H2C speaks http2 with prior knowledge on the http and http+unix schemes.
+	// The server must enable ServerOption.EnableH2C.
+	H2C bool
+
 	// http auth
 	Heads http.Header
 
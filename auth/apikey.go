@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/BabySid/gorpc/api"
+)
+
+var _ api.Authenticator = (*APIKey)(nil)
+
+// APIKey verifies static keys, either a bearer token of the Authorization
+// header or the value of a custom header like X-API-Key.
+type APIKey struct {
+	header string
+	// keys are indexed by the sha256 of the keys
+	keys map[[sha256.Size]byte]*api.Principal
+}
+
+// NewAPIKey returns an APIKey reading header, or the bearer token of the
+// Authorization header if header is empty. keys maps the keys to their subjects.
+// An unknown bearer token is left to the other authenticators, like a jwt,
+// while an unknown key of header is refused.
+func NewAPIKey(header string, keys map[string]string) *APIKey {
+	a := newAPIKey(header)
+	for key, subject := range keys {
+		a.add(key, subject, nil)
+	}
+	return a
+}
+
+// NewAPIKeyFromFile loads the keys from a file of lines like key subject [role,role].
+func NewAPIKeyFromFile(header string, path string) (*APIKey, error) {
+	lines, err := readLines(path, strings.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	a := newAPIKey(header)
+	for _, l := range lines {
+		fields := l.fields
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("invalid line %d of keys in %s", l.no, path)
+		}
+		var roles []string
+		if len(fields) == 3 {
+			roles = splitRoles(fields[2])
+		}
+		a.add(fields[0], fields[1], roles)
+	}
+	return a, nil
+}
+
+func newAPIKey(header string) *APIKey {
+	return &APIKey{header: header, keys: make(map[[sha256.Size]byte]*api.Principal)}
+}
+
+func (a *APIKey) add(key string, subject string, roles []string) {
+	scheme := "api_key"
+	if a.header == "" {
+		scheme = "bearer"
+	}
+	a.keys[sha256.Sum256([]byte(key))] = &api.Principal{Subject: subject, Scheme: scheme, Roles: roles}
+}
+
+func (a *APIKey) Authenticate(header http.Header) (*api.Principal, error) {
+	var key string
+	if a.header == "" {
+		key = bearerToken(header)
+	} else {
+		key = header.Get(a.header)
+	}
+	if key == "" {
+		return nil, nil
+	}
+
+	p, ok := a.keys[sha256.Sum256([]byte(key))]
+	if !ok && a.header == "" {
+		return nil, nil
+	}
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func bearerToken(header http.Header) string {
+	const prefix = "Bearer "
+	v := header.Get("Authorization")
+	if len(v) < len(prefix) || !strings.EqualFold(v[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(v[len(prefix):])
+}
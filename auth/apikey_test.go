@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/BabySid/gorpc/api"
+)
+
+func TestAPIKey(t *testing.T) {
+	keys := map[string]string{"k1": "svc1"}
+	tests := []struct {
+		name    string
+		header  string
+		request http.Header
+		want    *api.Principal
+		wantErr error
+	}{
+		{name: "header", header: "X-API-Key", request: http.Header{"X-Api-Key": {"k1"}},
+			want: &api.Principal{Subject: "svc1", Scheme: "api_key"}},
+		{name: "header missing", header: "X-API-Key", request: http.Header{"Authorization": {"Bearer k1"}}},
+		{name: "header unknown", header: "X-API-Key", request: http.Header{"X-Api-Key": {"k2"}}, wantErr: ErrInvalidCredentials},
+		{name: "bearer", request: http.Header{"Authorization": {"bearer k1"}},
+			want: &api.Principal{Subject: "svc1", Scheme: "bearer"}},
+		{name: "bearer missing", request: http.Header{"Authorization": {"Basic YTpi"}}},
+		// left to the other authenticators, e.g. a jwt
+		{name: "bearer unknown", request: http.Header{"Authorization": {"Bearer a.b.c"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewAPIKey(tt.header, keys).Authenticate(tt.request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Authenticate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+// Package auth provides the built-in api.Authenticator implementations.
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidToken       = errors.New("invalid token")
+)
+
+type line struct {
+	no     int
+	fields []string
+}
+
+// readLines returns the fields of the lines of a file, skipping the blank
+// lines and the comments which begin with #.
+func readLines(path string, sep func(string) []string) ([]line, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []line
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for no := 1; scanner.Scan(); no++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		lines = append(lines, line{no: no, fields: sep(text)})
+	}
+	return lines, scanner.Err()
+}
+
+func splitRoles(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
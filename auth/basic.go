@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/BabySid/gorpc/api"
+)
+
+const sha256Prefix = "{SHA256}"
+
+var _ api.Authenticator = (*Basic)(nil)
+
+// Basic verifies http basic auth, which is set by api.BasicAuth on the client.
+type Basic struct {
+	users map[string]basicUser
+}
+
+type basicUser struct {
+	password string
+	roles    []string
+}
+
+// NewBasic returns a Basic with the passwords of the users.
+func NewBasic(users map[string]string) *Basic {
+	b := &Basic{users: make(map[string]basicUser, len(users))}
+	for user, password := range users {
+		b.users[user] = basicUser{password: password}
+	}
+	return b
+}
+
+// NewBasicFromFile loads the users from a file of lines like user:password[:role,role].
+// The password is either plain text or {SHA256} followed by the hex sha256 of it.
+func NewBasicFromFile(path string) (*Basic, error) {
+	lines, err := readLines(path, func(line string) []string {
+		return strings.SplitN(line, ":", 3)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Basic{users: make(map[string]basicUser, len(lines))}
+	for _, l := range lines {
+		fields := l.fields
+		if len(fields) < 2 || fields[0] == "" {
+			return nil, fmt.Errorf("invalid line %d of users in %s", l.no, path)
+		}
+		u := basicUser{password: fields[1]}
+		if len(fields) == 3 {
+			u.roles = splitRoles(fields[2])
+		}
+		b.users[fields[0]] = u
+	}
+	return b, nil
+}
+
+func (b *Basic) Authenticate(header http.Header) (*api.Principal, error) {
+	r := http.Request{Header: header}
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+
+	u, ok := b.users[user]
+	if !ok || !u.match(password) {
+		return nil, ErrInvalidCredentials
+	}
+	return &api.Principal{Subject: user, Scheme: "basic", Roles: u.roles}, nil
+}
+
+func (u basicUser) match(password string) bool {
+	want := u.password
+	if strings.HasPrefix(want, sha256Prefix) {
+		sum := sha256.Sum256([]byte(password))
+		want = strings.ToLower(want[len(sha256Prefix):])
+		password = hex.EncodeToString(sum[:])
+	}
+	return subtle.ConstantTimeCompare([]byte(want), []byte(password)) == 1
+}
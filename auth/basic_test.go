@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/BabySid/gorpc/api"
+)
+
+func basicHeader(user, password string) http.Header {
+	r := http.Request{Header: http.Header{}}
+	r.SetBasicAuth(user, password)
+	return r.Header
+}
+
+func TestBasic(t *testing.T) {
+	sum := sha256.Sum256([]byte("s3cret"))
+	path := filepath.Join(t.TempDir(), "users")
+	data := "# users\nalice:pass:admin,dev\n\nbob:{SHA256}" + hex.EncodeToString(sum[:]) + "\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewBasicFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		want    *api.Principal
+		wantErr error
+	}{
+		{name: "no credentials", header: http.Header{}},
+		{name: "bearer", header: http.Header{"Authorization": {"Bearer x"}}},
+		{name: "plain", header: basicHeader("alice", "pass"),
+			want: &api.Principal{Subject: "alice", Scheme: "basic", Roles: []string{"admin", "dev"}}},
+		{name: "sha256", header: basicHeader("bob", "s3cret"), want: &api.Principal{Subject: "bob", Scheme: "basic"}},
+		{name: "wrong password", header: basicHeader("alice", "nope"), wantErr: ErrInvalidCredentials},
+		{name: "unknown user", header: basicHeader("carol", "pass"), wantErr: ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := b.Authenticate(tt.header)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Authenticate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewBasicFromFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "valid", data: "alice:pass\n"},
+		{name: "no password", data: "# users\n\nalice\n", wantErr: "invalid line 3 of users in "},
+		{name: "no user", data: ":pass\n", wantErr: "invalid line 1 of users in "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "users")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := NewBasicFromFile(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewBasicFromFile() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr+path {
+				t.Errorf("NewBasicFromFile() error = %v, want %s%s", err, tt.wantErr, path)
+			}
+		})
+	}
+}
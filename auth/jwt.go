@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BabySid/gorpc/api"
+)
+
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+)
+
+type JWTOption struct {
+	// Alg is HS256 or RS256.
+	Alg string
+	// KeyFile is the secret of HS256, or the PEM of the public key or
+	// certificate of RS256.
+	KeyFile string
+	// Issuer and Audience are checked if they are not empty.
+	Issuer   string
+	Audience string
+	// Leeway is the clock skew allowed for exp and nbf.
+	Leeway time.Duration
+}
+
+var _ api.Authenticator = (*JWT)(nil)
+
+// JWT verifies the bearer tokens of the Authorization header which are json web tokens.
+type JWT struct {
+	option JWTOption
+	secret []byte
+	key    *rsa.PublicKey
+}
+
+func NewJWT(option JWTOption) (*JWT, error) {
+	data, err := os.ReadFile(option.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &JWT{option: option}
+	switch option.Alg {
+	case HS256:
+		j.secret = []byte(strings.TrimSpace(string(data)))
+		if len(j.secret) == 0 {
+			return nil, fmt.Errorf("empty secret in %s", option.KeyFile)
+		}
+	case RS256:
+		if j.key, err = parseRSAPublicKey(data); err != nil {
+			return nil, fmt.Errorf("invalid key in %s: %w", option.KeyFile, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported jwt alg %q", option.Alg)
+	}
+	return j, nil
+}
+
+func parseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no pem block")
+	}
+
+	var pub interface{}
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		pub, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		pub, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			pub = cert.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("unsupported pem block %s", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not a rsa public key")
+	}
+	return key, nil
+}
+
+func (j *JWT) Authenticate(header http.Header) (*api.Principal, error) {
+	token := bearerToken(header)
+	// a bearer token which is not a jwt is left to other authenticators
+	if token == "" || strings.Count(token, ".") != 2 {
+		return nil, nil
+	}
+
+	claims, err := j.verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &api.Principal{Scheme: "jwt", Claims: claims}
+	p.Subject, _ = claims["sub"].(string)
+	p.Roles = stringsOf(claims["roles"])
+	if scope, ok := claims["scope"].(string); ok {
+		p.Scopes = strings.Fields(scope)
+	} else {
+		p.Scopes = stringsOf(claims["scp"])
+	}
+	return p, nil
+}
+
+func (j *JWT) verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+
+	var head struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &head); err != nil {
+		return nil, err
+	}
+	if head.Alg != j.option.Alg {
+		return nil, fmt.Errorf("%w: unexpected alg %q", ErrInvalidToken, head.Alg)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	switch j.option.Alg {
+	case HS256:
+		mac := hmac.New(sha256.New, j.secret)
+		mac.Write(signed)
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+	case RS256:
+		sum := sha256.Sum256(signed)
+		if err = rsa.VerifyPKCS1v15(j.key, crypto.SHA256, sum[:], sig); err != nil {
+			return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+	}
+
+	var claims map[string]interface{}
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err = j.validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (j *JWT) validate(claims map[string]interface{}) error {
+	now := time.Now()
+	if exp, ok := claims["exp"].(float64); ok && now.After(unixTime(exp).Add(j.option.Leeway)) {
+		return fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(j.option.Leeway).Before(unixTime(nbf)) {
+		return fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+	if j.option.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != j.option.Issuer {
+			return fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+		}
+	}
+	if j.option.Audience != "" {
+		aud := stringsOf(claims["aud"])
+		if s, ok := claims["aud"].(string); ok {
+			aud = []string{s}
+		}
+		found := false
+		for _, a := range aud {
+			if a == j.option.Audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+		}
+	}
+	return nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return nil
+}
+
+func unixTime(sec float64) time.Time {
+	return time.Unix(int64(sec), 0)
+}
+
+func stringsOf(v interface{}) []string {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	ss := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			ss = append(ss, s)
+		}
+	}
+	return ss
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func signHS256(t *testing.T, secret string, alg string, claims map[string]interface{}) string {
+	t.Helper()
+	enc := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := enc(map[string]string{"alg": alg, "typ": "JWT"}) + "." + enc(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestJWT(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(keyFile, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	j, err := NewJWT(JWTOption{Alg: HS256, KeyFile: keyFile, Issuer: "gorpc", Audience: "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := float64(time.Now().Unix())
+	valid := map[string]interface{}{"sub": "carol", "iss": "gorpc", "aud": "api", "exp": now + 60,
+		"roles": []string{"r1"}, "scope": "read write"}
+	with := func(k string, v interface{}) map[string]interface{} {
+		claims := make(map[string]interface{}, len(valid))
+		for k, v := range valid {
+			claims[k] = v
+		}
+		claims[k] = v
+		return claims
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantSub    string
+		wantScopes []string
+		wantErr    error
+	}{
+		{name: "no token"},
+		{name: "not a jwt", token: "opaque"},
+		{name: "valid", token: signHS256(t, "secret", HS256, valid), wantSub: "carol", wantScopes: []string{"read", "write"}},
+		{name: "aud list", token: signHS256(t, "secret", HS256, with("aud", []string{"x", "api"})), wantSub: "carol",
+			wantScopes: []string{"read", "write"}},
+		{name: "bad signature", token: signHS256(t, "other", HS256, valid), wantErr: ErrInvalidToken},
+		{name: "bad alg", token: signHS256(t, "secret", "none", valid), wantErr: ErrInvalidToken},
+		{name: "expired", token: signHS256(t, "secret", HS256, with("exp", now-60)), wantErr: ErrInvalidToken},
+		{name: "not valid yet", token: signHS256(t, "secret", HS256, with("nbf", now+60)), wantErr: ErrInvalidToken},
+		{name: "bad issuer", token: signHS256(t, "secret", HS256, with("iss", "x")), wantErr: ErrInvalidToken},
+		{name: "bad audience", token: signHS256(t, "secret", HS256, with("aud", "x")), wantErr: ErrInvalidToken},
+		{name: "malformed", token: "a.b.c", wantErr: ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.token != "" {
+				header.Set("Authorization", "Bearer "+tt.token)
+			}
+			got, err := j.Authenticate(header)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantSub == "" {
+				if got != nil {
+					t.Errorf("Authenticate() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || got.Subject != tt.wantSub || got.Scheme != "jwt" || !reflect.DeepEqual(got.Scopes, tt.wantScopes) {
+				t.Errorf("Authenticate() = %+v, want subject %s and scopes %v", got, tt.wantSub, tt.wantScopes)
+			}
+		})
+	}
+}
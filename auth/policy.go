@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/BabySid/gorpc/api"
+)
+
+// LoadPolicies loads the policies of api.AuthOption from a file of lines like
+//
+//	# pattern    requirements
+//	admin.*      roles=admin
+//	rpc.Sub      scopes=stream
+//	/pkg.Svc/*   roles=ops,dev scopes=read
+func LoadPolicies(file string) ([]api.Policy, error) {
+	lines, err := readLines(file, strings.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make([]api.Policy, 0, len(lines))
+	for _, l := range lines {
+		policy := api.Policy{Pattern: l.fields[0]}
+		if _, err = path.Match(policy.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q at line %d of policies in %s", policy.Pattern, l.no, file)
+		}
+		for _, field := range l.fields[1:] {
+			k, v, ok := strings.Cut(field, "=")
+			switch {
+			case ok && k == "roles":
+				policy.Roles = append(policy.Roles, splitRoles(v)...)
+			case ok && k == "scopes":
+				policy.Scopes = append(policy.Scopes, splitRoles(v)...)
+			default:
+				return nil, fmt.Errorf("invalid requirement %q at line %d of policies in %s", field, l.no, file)
+			}
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/BabySid/gorpc/api"
+)
+
+func TestLoadPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []api.Policy
+		wantErr string
+	}{
+		{
+			name: "valid",
+			data: "# pattern requirements\nadmin.*  roles=admin\n\n/pkg.Svc/*  roles=ops,dev scopes=read\n",
+			want: []api.Policy{
+				{Pattern: "admin.*", Roles: []string{"admin"}},
+				{Pattern: "/pkg.Svc/*", Roles: []string{"ops", "dev"}, Scopes: []string{"read"}},
+			},
+		},
+		{name: "bad pattern", data: "# bad\nadmin[ roles=admin\n", wantErr: `invalid pattern "admin[" at line 2 of policies in `},
+		{name: "bad requirement", data: "\n\nrpc.Sub stream\n", wantErr: `invalid requirement "stream" at line 3 of policies in `},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policies")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadPolicies(path)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr+path {
+					t.Fatalf("LoadPolicies() error = %v, want %s%s", err, tt.wantErr, path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadPolicies() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadPolicies() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -16,12 +16,12 @@ func Dial(rawUrl string, opt api.ClientOption) (api.Client, error) {
 	}
 
 	switch u.Scheme {
-	case "http", "https":
+	case "http", "https", "http+unix":
 		return http.Dial(rawUrl, opt)
-	case "ws", "wss":
+	case "ws", "wss", "ws+unix":
 		return websocket.Dial(rawUrl, opt)
-	case "grpc":
-		return grpc.Dial(rawUrl)
+	case "grpc", "grpcs", "grpc+unix":
+		return grpc.Dial(rawUrl, opt)
 	default:
 		return nil, fmt.Errorf("no known transport for URL scheme %q", u.Scheme)
 	}
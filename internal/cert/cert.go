@@ -0,0 +1,140 @@
+package cert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/log"
+)
+
+const reloadCheckInterval = 10 * time.Second
+
+var errNoCertificate = errors.New("tls: no certificate configured")
+
+// ServerConfig returns the tls config which terminates tls on the listener.
+func ServerConfig(opt *api.TLSOption) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if opt.Config != nil {
+		cfg = opt.Config.Clone()
+	}
+
+	if opt.CertFile != "" || opt.KeyFile != "" {
+		r, err := newReloader(opt.CertFile, opt.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.GetCertificate = r.getCertificate
+	}
+
+	if opt.ClientCAFile != "" {
+		pem, err := os.ReadFile(opt.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls: no certificate found in %s", opt.ClientCAFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = opt.ClientAuth
+		if cfg.ClientAuth == tls.NoClientCert {
+			cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
+	if len(cfg.Certificates) == 0 && cfg.GetCertificate == nil && cfg.GetConfigForClient == nil {
+		return nil, errNoCertificate
+	}
+
+	// grpc clients require h2 to be negotiated
+	if len(cfg.NextProtos) == 0 {
+		cfg.NextProtos = []string{"h2", "http/1.1"}
+	}
+	return cfg, nil
+}
+
+// reloader loads the key pair again once the modification time
+// of either file changes.
+type reloader struct {
+	certFile string
+	keyFile  string
+
+	mux       sync.Mutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	checkedAt time.Time
+}
+
+func newReloader(certFile, keyFile string) (*reloader, error) {
+	r := &reloader{certFile: certFile, keyFile: keyFile}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *reloader) load() error {
+	certMod, keyMod, err := r.modTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.cert = &cert
+	r.certMod = certMod
+	r.keyMod = keyMod
+	r.checkedAt = time.Now()
+	return nil
+}
+
+func (r *reloader) modTime() (time.Time, time.Time, error) {
+	certStat, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	keyStat, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return certStat.ModTime(), keyStat.ModTime(), nil
+}
+
+func (r *reloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if time.Since(r.checkedAt) < reloadCheckInterval {
+		return r.cert, nil
+	}
+	r.checkedAt = time.Now()
+
+	certMod, keyMod, err := r.modTime()
+	if err != nil {
+		log.DefaultLog.Warn("stat certificate failed", slog.String("certFile", r.certFile), slog.Any("err", err))
+		return r.cert, nil
+	}
+	if certMod.Equal(r.certMod) && keyMod.Equal(r.keyMod) {
+		return r.cert, nil
+	}
+
+	// keep serving the old certificate if the new one is broken,
+	// e.g. only one of the files has been replaced yet.
+	if err = r.load(); err != nil {
+		log.DefaultLog.Warn("reload certificate failed", slog.String("certFile", r.certFile), slog.Any("err", err))
+		return r.cert, nil
+	}
+	log.DefaultLog.Info("certificate reloaded", slog.String("certFile", r.certFile))
+	return r.cert, nil
+}
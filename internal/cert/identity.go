@@ -0,0 +1,73 @@
+package cert
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"github.com/BabySid/gorpc/api"
+	"github.com/soheilhy/cmux"
+)
+
+type connKey struct{}
+
+// WithConn is used as http.Server.ConnContext, so that the tls state
+// of a request can be found after cmux hides the *tls.Conn.
+func WithConn(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, c)
+}
+
+// RequestIdentity returns the identity of the verified client certificate of r.
+func RequestIdentity(r *http.Request) *api.PeerIdentity {
+	if r.TLS != nil {
+		return Identity(r.TLS)
+	}
+	c, ok := r.Context().Value(connKey{}).(net.Conn)
+	if !ok {
+		return nil
+	}
+	return Identity(ConnState(c))
+}
+
+// ConnState returns the tls state of c, looking through the wrappers around the *tls.Conn.
+func ConnState(c net.Conn) *tls.ConnectionState {
+	for {
+		switch conn := c.(type) {
+		case *tls.Conn:
+			state := conn.ConnectionState()
+			return &state
+		case *cmux.MuxConn:
+			c = conn.Conn
+		case interface{ NetConn() net.Conn }:
+			c = conn.NetConn()
+		default:
+			return nil
+		}
+	}
+}
+
+// Identity returns the identity of the leaf of the verified peer certificate chain.
+func Identity(state *tls.ConnectionState) *api.PeerIdentity {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	leaf := state.VerifiedChains[0][0]
+	id := &api.PeerIdentity{
+		Subject:        leaf.Subject.String(),
+		CommonName:     leaf.Subject.CommonName,
+		DNSNames:       leaf.DNSNames,
+		EmailAddresses: leaf.EmailAddresses,
+		IPAddresses:    leaf.IPAddresses,
+		URIs:           leaf.URIs,
+		Certificate:    leaf,
+	}
+	for _, uri := range leaf.URIs {
+		if uri.Scheme == "spiffe" {
+			id.SpiffeID = uri.String()
+			break
+		}
+	}
+	return id
+}
@@ -0,0 +1,145 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/metrics"
+)
+
+var (
+	ErrQueueFull    = errors.New("too many calls in flight")
+	ErrQueueTimeout = errors.New("timed out waiting for a slot")
+)
+
+// Limiter applies api.ConcurrencyLimit to a bulkhead.
+type Limiter struct {
+	name string
+	opt  api.ConcurrencyLimit
+
+	mux      sync.Mutex
+	limit    int
+	inFlight int
+	waiters  []chan struct{}
+
+	// the latency of the current window of the adaptive limit
+	sum   time.Duration
+	count int
+}
+
+// NewLimiter returns the Limiter of the bulkhead name, which is a label of the metrics.
+func NewLimiter(name string, opt api.ConcurrencyLimit) *Limiter {
+	if opt.Adaptive != nil && opt.Adaptive.MinInFlight <= 0 {
+		adaptive := *opt.Adaptive
+		adaptive.MinInFlight = 1
+		opt.Adaptive = &adaptive
+	}
+	return &Limiter{name: name, opt: opt, limit: opt.MaxInFlight}
+}
+
+// Acquire takes a slot, waiting in the queue if there is none until ctx is done.
+// The returned func must be called once the call is done. Every call gets a
+// slot at once if MaxInFlight is not positive.
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	if l.opt.MaxInFlight <= 0 {
+		return func() {}, nil
+	}
+
+	l.mux.Lock()
+	if l.inFlight < l.limit && len(l.waiters) == 0 {
+		l.inFlight++
+		l.mux.Unlock()
+		return l.releaser(), nil
+	}
+	if len(l.waiters) >= l.opt.MaxQueue {
+		l.mux.Unlock()
+		return nil, ErrQueueFull
+	}
+	w := make(chan struct{})
+	l.waiters = append(l.waiters, w)
+	l.mux.Unlock()
+
+	var timeout <-chan time.Time
+	if l.opt.QueueTimeout > 0 {
+		timer := time.NewTimer(l.opt.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-w:
+		return l.releaser(), nil
+	case <-timeout:
+		return l.leave(w, ErrQueueTimeout)
+	case <-ctx.Done():
+		return l.leave(w, ctx.Err())
+	}
+}
+
+// leave removes w from the queue and returns err, or takes the slot if it is
+// handed over meanwhile.
+func (l *Limiter) leave(w chan struct{}, err error) (func(), error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	for i, waiter := range l.waiters {
+		if waiter == w {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			return nil, err
+		}
+	}
+	return l.releaser(), nil
+}
+
+func (l *Limiter) releaser() func() {
+	start := time.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.release(time.Since(start))
+		})
+	}
+}
+
+func (l *Limiter) release(latency time.Duration) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.inFlight--
+	l.observe(latency)
+	metrics.ConcurrencyLimit.WithLabelValues(metrics.GetCluster(), l.name).Set(float64(l.limit))
+	for l.inFlight < l.limit && len(l.waiters) > 0 {
+		w := l.waiters[0]
+		l.waiters = l.waiters[1:]
+		l.inFlight++
+		close(w)
+	}
+}
+
+// observe adjusts the adaptive limit once a window of calls is done.
+func (l *Limiter) observe(latency time.Duration) {
+	adaptive := l.opt.Adaptive
+	if adaptive == nil {
+		return
+	}
+
+	l.sum += latency
+	l.count++
+	if l.count < l.limit {
+		return
+	}
+	avg := l.sum / time.Duration(l.count)
+	l.sum, l.count = 0, 0
+
+	if avg > adaptive.TargetLatency {
+		l.limit -= (l.limit + 9) / 10
+		if l.limit < adaptive.MinInFlight {
+			l.limit = adaptive.MinInFlight
+		}
+	} else if l.limit < l.opt.MaxInFlight {
+		l.limit++
+	}
+}
@@ -0,0 +1,136 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BabySid/gorpc/api"
+)
+
+func TestLimiterAcquire(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  api.ConcurrencyLimit
+		// held are the slots taken before the call under test
+		held    int
+		cancel  bool
+		wantErr error
+	}{
+		{name: "free slot", opt: api.ConcurrencyLimit{MaxInFlight: 2}, held: 1},
+		{name: "no queue", opt: api.ConcurrencyLimit{MaxInFlight: 1}, held: 1, wantErr: ErrQueueFull},
+		{name: "queue timeout", opt: api.ConcurrencyLimit{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: 20 * time.Millisecond},
+			held: 1, wantErr: ErrQueueTimeout},
+		{name: "ctx done", opt: api.ConcurrencyLimit{MaxInFlight: 1, MaxQueue: 1}, held: 1, cancel: true, wantErr: context.Canceled},
+		{name: "no limit", opt: api.ConcurrencyLimit{}, held: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLimiter("test", tt.opt)
+			for i := 0; i < tt.held; i++ {
+				if _, err := l.Acquire(context.Background()); err != nil {
+					t.Fatalf("Acquire() of slot %d error = %v", i, err)
+				}
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				time.AfterFunc(20*time.Millisecond, cancel)
+			}
+			release, err := l.Acquire(ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Acquire() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				release()
+			}
+			if len(l.waiters) != 0 {
+				t.Errorf("%d waiters are left in the queue", len(l.waiters))
+			}
+		})
+	}
+}
+
+func TestLimiterQueue(t *testing.T) {
+	l := NewLimiter("test", api.ConcurrencyLimit{MaxInFlight: 1, MaxQueue: 2})
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the waiters get the slot in order
+	order := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		i := i
+		go func() {
+			r, err := l.Acquire(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			order <- i
+			r()
+		}()
+		waitFor(t, func() bool { return queued(l) == i+1 })
+	}
+	if _, err = l.Acquire(context.Background()); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Acquire() of a full queue error = %v, want %v", err, ErrQueueFull)
+	}
+
+	release()
+	release() // a second release is a no-op
+	for want := 0; want < 2; want++ {
+		if got := <-order; got != want {
+			t.Errorf("waiter %d got the slot, want %d", got, want)
+		}
+	}
+}
+
+func TestLimiterAdaptive(t *testing.T) {
+	tests := []struct {
+		name      string
+		latency   time.Duration
+		windows   int
+		wantLimit int
+	}{
+		{name: "slow calls decrease the limit", latency: time.Second, windows: 1, wantLimit: 9},
+		{name: "down to the min", latency: time.Second, windows: 100, wantLimit: 2},
+		{name: "fast calls keep the max", latency: time.Millisecond, windows: 3, wantLimit: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLimiter("test", api.ConcurrencyLimit{MaxInFlight: 10,
+				Adaptive: &api.AdaptiveLimit{TargetLatency: 100 * time.Millisecond, MinInFlight: 2}})
+			for w := 0; w < tt.windows; w++ {
+				for n := l.limit; n > 0; n-- {
+					l.inFlight++
+					l.release(tt.latency)
+				}
+			}
+			if l.limit != tt.wantLimit {
+				t.Errorf("limit = %d, want %d", l.limit, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func queued(l *Limiter) int {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return len(l.waiters)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition is not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
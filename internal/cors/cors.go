@@ -0,0 +1,161 @@
+package cors
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/BabySid/gorpc/api"
+)
+
+var defaultMethods = []string{http.MethodGet, http.MethodPost, http.MethodHead}
+
+// Cors applies api.CorsOption.
+type Cors struct {
+	opt     *api.CorsOption
+	methods []string
+	// anyOrigin is set by the origin "*", which is answered by a literal *
+	// without credentials
+	anyOrigin bool
+	// origins are the other allowed origins
+	origins []string
+}
+
+func New(opt *api.CorsOption) *Cors {
+	c := &Cors{opt: opt, methods: opt.AllowedMethods}
+	if len(c.methods) == 0 {
+		c.methods = defaultMethods
+	}
+	for _, o := range opt.AllowedOrigins {
+		if o == "*" {
+			c.anyOrigin = true
+		} else {
+			c.origins = append(c.origins, strings.ToLower(o))
+		}
+	}
+	return c
+}
+
+// Handle sets the cors headers of the response to r. It returns true if r is
+// a preflight, which has been answered.
+func (c *Cors) Handle(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+
+	hd := w.Header()
+	if !isPreflight(r) {
+		hd.Add("Vary", "Origin")
+		if c.AllowOrigin(origin) {
+			c.SetOrigin(hd, origin)
+			if len(c.opt.ExposedHeaders) > 0 {
+				hd.Set("Access-Control-Expose-Headers", strings.Join(c.opt.ExposedHeaders, ", "))
+			}
+		}
+		return false
+	}
+
+	hd.Add("Vary", "Origin")
+	hd.Add("Vary", "Access-Control-Request-Method")
+	hd.Add("Vary", "Access-Control-Request-Headers")
+	method := r.Header.Get("Access-Control-Request-Method")
+	headers := r.Header.Get("Access-Control-Request-Headers")
+	if !c.AllowOrigin(origin) || !contains(c.methods, method) || !c.allowHeaders(headers) {
+		w.WriteHeader(http.StatusForbidden)
+		return true
+	}
+
+	c.SetOrigin(hd, origin)
+	hd.Set("Access-Control-Allow-Methods", strings.Join(c.methods, ", "))
+	if len(c.opt.AllowedHeaders) > 0 {
+		hd.Set("Access-Control-Allow-Headers", strings.Join(c.opt.AllowedHeaders, ", "))
+	} else if headers != "" {
+		hd.Set("Access-Control-Allow-Headers", headers)
+	}
+	c.SetMaxAge(hd)
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// AllowOrigin reports whether origin matches the allowed origins.
+func (c *Cors) AllowOrigin(origin string) bool {
+	return c.anyOrigin || c.matchOrigin(origin)
+}
+
+// matchOrigin reports whether origin matches the allowed origins other than "*".
+func (c *Cors) matchOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, o := range c.origins {
+		if o == origin {
+			return true
+		}
+		if matched, _ := path.Match(o, origin); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckOrigin is the origin check of the websocket handshakes, which allows
+// the clients other than browsers, the same origin and the allowed origins.
+// "*" does not apply, since the browsers send the cookies with a handshake
+// of any origin, and the websocket would be hijacked by any site.
+func (c *Cors) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return c.matchOrigin(origin)
+}
+
+// SetOrigin sets Access-Control-Allow-Origin of the response to an allowed
+// origin. The origin is echoed with the credentials if it is allowed by name,
+// otherwise it is allowed by "*", which never comes with the credentials.
+func (c *Cors) SetOrigin(hd http.Header, origin string) {
+	if !c.matchOrigin(origin) {
+		hd.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	hd.Set("Access-Control-Allow-Origin", origin)
+	if c.opt.AllowCredentials {
+		hd.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// SetMaxAge sets Access-Control-Max-Age of a preflight if MaxAge is positive.
+func (c *Cors) SetMaxAge(hd http.Header) {
+	if c.opt.MaxAge > 0 {
+		hd.Set("Access-Control-Max-Age", strconv.Itoa(int(c.opt.MaxAge.Seconds())))
+	}
+}
+
+func (c *Cors) allowHeaders(headers string) bool {
+	if len(c.opt.AllowedHeaders) == 0 || headers == "" {
+		return true
+	}
+	for _, h := range strings.Split(headers, ",") {
+		if h = strings.TrimSpace(h); h != "" && !contains(c.opt.AllowedHeaders, h) {
+			return false
+		}
+	}
+	return true
+}
+
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+func contains(items []string, s string) bool {
+	for _, item := range items {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
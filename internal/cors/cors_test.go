@@ -0,0 +1,112 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BabySid/gorpc/api"
+)
+
+func TestHandle(t *testing.T) {
+	named := &api.CorsOption{
+		AllowedOrigins:   []string{"https://example.com", "https://*.example.org"},
+		AllowedHeaders:   []string{"Content-Type"},
+		AllowCredentials: true,
+	}
+	anyOrigin := &api.CorsOption{
+		AllowedOrigins:   []string{"https://example.com", "*"},
+		AllowCredentials: true,
+	}
+
+	tests := []struct {
+		name        string
+		opt         *api.CorsOption
+		method      string
+		origin      string
+		reqMethod   string
+		reqHeaders  string
+		wantHandled bool
+		wantCode    int
+		wantOrigin  string
+		wantCreds   bool
+	}{
+		{name: "no origin", opt: named, method: http.MethodPost},
+		{name: "named", opt: named, method: http.MethodPost, origin: "https://example.com",
+			wantOrigin: "https://example.com", wantCreds: true},
+		{name: "pattern", opt: named, method: http.MethodPost, origin: "https://api.Example.org",
+			wantOrigin: "https://api.Example.org", wantCreds: true},
+		{name: "not allowed", opt: named, method: http.MethodPost, origin: "https://evil.com"},
+		{name: "any", opt: anyOrigin, method: http.MethodPost, origin: "https://evil.com", wantOrigin: "*"},
+		{name: "named besides any", opt: anyOrigin, method: http.MethodPost, origin: "https://example.com",
+			wantOrigin: "https://example.com", wantCreds: true},
+		{name: "preflight", opt: named, method: http.MethodOptions, origin: "https://example.com",
+			reqMethod: http.MethodPost, reqHeaders: "content-type", wantHandled: true, wantCode: http.StatusNoContent,
+			wantOrigin: "https://example.com", wantCreds: true},
+		{name: "preflight of any", opt: anyOrigin, method: http.MethodOptions, origin: "https://evil.com",
+			reqMethod: http.MethodPost, wantHandled: true, wantCode: http.StatusNoContent, wantOrigin: "*"},
+		{name: "preflight of origin not allowed", opt: named, method: http.MethodOptions, origin: "https://evil.com",
+			reqMethod: http.MethodPost, wantHandled: true, wantCode: http.StatusForbidden},
+		{name: "preflight of method not allowed", opt: named, method: http.MethodOptions, origin: "https://example.com",
+			reqMethod: http.MethodDelete, wantHandled: true, wantCode: http.StatusForbidden},
+		{name: "preflight of header not allowed", opt: named, method: http.MethodOptions, origin: "https://example.com",
+			reqMethod: http.MethodPost, reqHeaders: "X-Token", wantHandled: true, wantCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.reqMethod != "" {
+				r.Header.Set("Access-Control-Request-Method", tt.reqMethod)
+			}
+			if tt.reqHeaders != "" {
+				r.Header.Set("Access-Control-Request-Headers", tt.reqHeaders)
+			}
+			w := httptest.NewRecorder()
+
+			if got := New(tt.opt).Handle(w, r); got != tt.wantHandled {
+				t.Fatalf("Handle() = %v, want %v", got, tt.wantHandled)
+			}
+			if tt.wantHandled && w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.wantCreds {
+				t.Errorf("Access-Control-Allow-Credentials = %v, want %v", got, tt.wantCreds)
+			}
+		})
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		origin  string
+		want    bool
+	}{
+		{name: "no origin", origins: nil, want: true},
+		{name: "same origin", origins: nil, origin: "http://example.com", want: true},
+		{name: "named", origins: []string{"https://app.com"}, origin: "https://app.com", want: true},
+		{name: "pattern", origins: []string{"https://*.app.com"}, origin: "https://web.app.com", want: true},
+		{name: "not allowed", origins: []string{"https://app.com"}, origin: "https://evil.com"},
+		{name: "any is not for websockets", origins: []string{"*"}, origin: "https://evil.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://example.com/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := New(&api.CorsOption{AllowedOrigins: tt.origins}).CheckOrigin(r); got != tt.want {
+				t.Errorf("CheckOrigin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package ctx
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"sync/atomic"
 	"time"
 
 	"github.com/BabySid/gobase/log"
@@ -12,14 +14,47 @@ import (
 
 var _ api.Context = (*ContextAdapter)(nil)
 
+// Processing counts the processing requests of a server, so that its
+// shutdown can wait for them.
+type Processing struct {
+	n atomic.Int64
+}
+
+// BeginRequest marks a request as processing. Every call must be paired
+// with ContextAdapter.EndRequest of an adapter whose Processing is p.
+func (p *Processing) BeginRequest(name string, reqSize int) {
+	p.n.Add(1)
+	metrics.ProcessingRequests.WithLabelValues(metrics.GetCluster(), name).Inc()
+	metrics.RealTimeRequestBodySize.WithLabelValues(metrics.GetCluster(), name).Set(float64(reqSize))
+}
+
+// Wait blocks until there are no processing requests or ctx is done.
+func (p *Processing) Wait(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for p.n.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
 type ContextAdapter struct {
 	Name    string
 	RevTime time.Time
 	ID      interface{}
+	// Ctx is the context of the request, or of the websocket session.
+	Ctx context.Context
 
 	KV map[string]any
 
 	Logger log.Logger
+	// Processing counts the request until EndRequest, if it is not nil.
+	Processing *Processing
 }
 
 func (ctx *ContextAdapter) ClientIP() string {
@@ -27,6 +62,11 @@ func (ctx *ContextAdapter) ClientIP() string {
 	panic("implement me")
 }
 
+func (ctx *ContextAdapter) PeerIdentity() *api.PeerIdentity {
+	// TODO implement me
+	panic("implement me")
+}
+
 func (ctx *ContextAdapter) WithValue(key string, value any) {
 	ctx.KV[key] = value
 }
@@ -36,15 +76,41 @@ func (ctx *ContextAdapter) Value(key string) (any, bool) {
 	return v, ok
 }
 
+func (ctx *ContextAdapter) Context() context.Context {
+	if ctx.Ctx == nil {
+		return context.Background()
+	}
+	return ctx.Ctx
+}
+
 func (ctx *ContextAdapter) CtxID() interface{} {
 	return ctx.ID
 }
 
 func (ctx *ContextAdapter) EndRequest(code int) {
 	ctx.Logger.Info("EndRequest", slog.Int("code", code), slog.Int("cost", int(time.Since(ctx.RevTime))))
+	ctx.end([]int{code})
+}
+
+// EndRequests is EndRequest of a json-rpc batch, which counts the codes of
+// its requests one by one. An empty batch is a success.
+func (ctx *ContextAdapter) EndRequests(codes []int) {
+	ctx.Logger.Info("EndRequest", slog.Any("codes", codes), slog.Int("cost", int(time.Since(ctx.RevTime))))
+	if len(codes) == 0 {
+		codes = []int{api.Success}
+	}
+	ctx.end(codes)
+}
 
+func (ctx *ContextAdapter) end(codes []int) {
+	cost := time.Since(ctx.RevTime)
+	if ctx.Processing != nil {
+		ctx.Processing.n.Add(-1)
+	}
 	metrics.ProcessingRequests.WithLabelValues(metrics.GetCluster(), ctx.Name).Dec()
-	metrics.TotalRequests.WithLabelValues(metrics.GetCluster(), ctx.Name, fmt.Sprintf("%d", code)).Inc()
-	metrics.RequestLatency.WithLabelValues(metrics.GetCluster(), ctx.Name).Observe(float64(time.Since(ctx.RevTime).Milliseconds()))
-	metrics.RealTimeRequestLatency.WithLabelValues(metrics.GetCluster(), ctx.Name).Set(float64(time.Since(ctx.RevTime).Milliseconds()))
+	for _, code := range codes {
+		metrics.TotalRequests.WithLabelValues(metrics.GetCluster(), ctx.Name, fmt.Sprintf("%d", code)).Inc()
+		metrics.RequestLatency.WithLabelValues(metrics.GetCluster(), ctx.Name, fmt.Sprintf("%d", code)).Observe(float64(cost.Milliseconds()))
+	}
+	metrics.RealTimeRequestLatency.WithLabelValues(metrics.GetCluster(), ctx.Name).Set(float64(cost.Milliseconds()))
 }
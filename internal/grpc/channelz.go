@@ -0,0 +1,115 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	channelzpb "google.golang.org/grpc/channelz/grpc_channelz_v1"
+	"google.golang.org/grpc/channelz/service"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// czRegistrar keeps the channelz implementation registered to the grpc server,
+// so that the summary page reads the same data as the grpc service.
+type czRegistrar struct {
+	s    *grpc.Server
+	impl channelzpb.ChannelzServer
+}
+
+func (r *czRegistrar) RegisterService(desc *grpc.ServiceDesc, impl interface{}) {
+	r.impl = impl.(channelzpb.ChannelzServer)
+	r.s.RegisterService(desc, impl)
+}
+
+func registerChannelz(s *grpc.Server) channelzpb.ChannelzServer {
+	r := &czRegistrar{s: s}
+	service.RegisterChannelzServiceToServer(r)
+	return r.impl
+}
+
+// ChannelzHandler serves a html summary of the grpc servers and channels
+// of the process. It is nil unless channelz is enabled.
+func (s *Server) ChannelzHandler() http.Handler {
+	if s.channelz == nil {
+		return nil
+	}
+	return http.HandlerFunc(s.serveChannelz)
+}
+
+func (s *Server) serveChannelz(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+	if err := s.writeChannelz(r.Context(), &b); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(b.String()))
+}
+
+func (s *Server) writeChannelz(ctx context.Context, b *strings.Builder) error {
+	b.WriteString(`
+<h2>Servers</h2>
+<table border="1">
+  <tr>
+    <th>Id</th><th>Listen Sockets</th><th>Calls Started</th><th>Succeeded</th><th>Failed</th><th>Last Call</th>
+  </tr>
+`)
+	for start := int64(0); ; {
+		resp, err := s.channelz.GetServers(ctx, &channelzpb.GetServersRequest{StartServerId: start})
+		if err != nil {
+			return err
+		}
+		for _, svr := range resp.Server {
+			var socks []string
+			for _, sock := range svr.ListenSocket {
+				socks = append(socks, html.EscapeString(sock.Name))
+			}
+			d := svr.Data
+			fmt.Fprintf(b, "  <tr><td>%d</td><td>%s</td><td>%d</td><td>%d</td><td>%d</td><td>%s</td></tr>\n",
+				svr.Ref.ServerId, strings.Join(socks, "<br>"),
+				d.CallsStarted, d.CallsSucceeded, d.CallsFailed, timestamp(d.LastCallStartedTimestamp))
+			start = svr.Ref.ServerId + 1
+		}
+		if resp.End || len(resp.Server) == 0 {
+			break
+		}
+	}
+	b.WriteString("</table>\n")
+
+	b.WriteString(`
+<h2>Channels</h2>
+<table border="1">
+  <tr>
+    <th>Id</th><th>Target</th><th>State</th><th>Calls Started</th><th>Succeeded</th><th>Failed</th><th>Last Call</th>
+  </tr>
+`)
+	for start := int64(0); ; {
+		resp, err := s.channelz.GetTopChannels(ctx, &channelzpb.GetTopChannelsRequest{StartChannelId: start})
+		if err != nil {
+			return err
+		}
+		for _, ch := range resp.Channel {
+			d := ch.Data
+			fmt.Fprintf(b, "  <tr><td>%d</td><td>%s</td><td>%s</td><td>%d</td><td>%d</td><td>%d</td><td>%s</td></tr>\n",
+				ch.Ref.ChannelId, html.EscapeString(d.Target), d.GetState().GetState(),
+				d.CallsStarted, d.CallsSucceeded, d.CallsFailed, timestamp(d.LastCallStartedTimestamp))
+			start = ch.Ref.ChannelId + 1
+		}
+		if resp.End || len(resp.Channel) == 0 {
+			break
+		}
+	}
+	b.WriteString("</table>\n")
+	return nil
+}
+
+func timestamp(ts *timestamppb.Timestamp) string {
+	if ts == nil || (ts.Seconds == 0 && ts.Nanos == 0) {
+		return "-"
+	}
+	return ts.AsTime().Local().Format("2006-01-02 15:04:05")
+}
@@ -2,7 +2,9 @@ package grpc
 
 import (
 	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/netutil"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"net/url"
 )
@@ -25,13 +27,26 @@ func (c *Client) UnderlyingHandle() interface{} {
 	return c.ClientConn
 }
 
-func Dial(rawUrl string) (*Client, error) {
+func Dial(rawUrl string, opt api.ClientOption) (*Client, error) {
 	u, err := url.Parse(rawUrl)
 	if err != nil {
 		return nil, err
 	}
 
-	conn, err := grpc.Dial(u.Host, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	target := u.Host
+	creds := insecure.NewCredentials()
+	switch u.Scheme {
+	case "grpcs":
+		creds = credentials.NewTLS(opt.TLSConfig)
+	case "grpc+unix":
+		sockPath, _, err := netutil.SplitUnixURL(rawUrl)
+		if err != nil {
+			return nil, err
+		}
+		target = netutil.UnixScheme + "://" + sockPath
+	}
+
+	conn, err := grpc.Dial(target, grpc.WithTransportCredentials(creds))
 	c := Client{ClientConn: conn}
 	return &c, err
 }
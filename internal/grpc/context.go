@@ -0,0 +1,78 @@
+package grpc
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/cert"
+	"github.com/BabySid/gorpc/internal/ctx"
+	"github.com/BabySid/gorpc/internal/log"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+var _ api.Context = (*Context)(nil)
+
+// Context adapts the context of a grpc call to api.Context.
+type Context struct {
+	ctx context.Context
+	ctx.ContextAdapter
+	// gateway is the http client of a call forwarded by the grpc-gateway
+	gateway *gatewayPeer
+}
+
+func (ctx *Context) ClientIP() string {
+	if ctx.gateway != nil {
+		return ctx.gateway.clientIP
+	}
+	pr, ok := peer.FromContext(ctx.ctx)
+	if !ok || pr.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(pr.Addr.String())
+	if err != nil {
+		return pr.Addr.String()
+	}
+	return host
+}
+
+func (ctx *Context) PeerIdentity() *api.PeerIdentity {
+	if ctx.gateway != nil {
+		return ctx.gateway.identity
+	}
+	pr, ok := peer.FromContext(ctx.ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := pr.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+	return cert.Identity(&info.State)
+}
+
+func NewContext(c context.Context) *Context {
+	return newContext(c, "grpc", nil)
+}
+
+func newContext(c context.Context, name string, gateway *gatewayPeer) *Context {
+	grpcCtx := &Context{
+		ctx:     c,
+		gateway: gateway,
+		ContextAdapter: ctx.ContextAdapter{
+			Name:    name,
+			RevTime: time.Now(),
+			ID:      uuid.New().String(),
+			Ctx:     c,
+			KV:      make(map[string]any),
+			Logger:  nil,
+		},
+	}
+
+	grpcCtx.Logger = log.DefaultLog.WithOut(slog.String("name", grpcCtx.Name), slog.Any("ctxID", grpcCtx.ID), slog.String("clientIP", grpcCtx.ClientIP()))
+	return grpcCtx
+}
@@ -0,0 +1,44 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/BabySid/gorpc/internal/cert"
+	"google.golang.org/grpc/credentials"
+)
+
+var _ credentials.TransportCredentials = (*tlsInfoCreds)(nil)
+
+// tlsInfoCreds does no handshake itself. Tls is terminated on the listener
+// before cmux, so it only exposes the state of that handshake as the
+// AuthInfo of the peer.
+type tlsInfoCreds struct{}
+
+func (c *tlsInfoCreds) ClientHandshake(context.Context, string, net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.New("tlsInfoCreds: client handshake is not supported")
+}
+
+func (c *tlsInfoCreds) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	state := cert.ConnState(conn)
+	if state == nil {
+		return conn, nil, nil
+	}
+	return conn, credentials.TLSInfo{
+		State:          *state,
+		CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity},
+	}, nil
+}
+
+func (c *tlsInfoCreds) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "tls", SecurityVersion: "1.2"}
+}
+
+func (c *tlsInfoCreds) Clone() credentials.TransportCredentials {
+	return &tlsInfoCreds{}
+}
+
+func (c *tlsInfoCreds) OverrideServerName(string) error {
+	return nil
+}
@@ -0,0 +1,73 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/health"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const healthWatchInterval = 5 * time.Second
+
+var _ grpc_health_v1.HealthServer = (*healthServer)(nil)
+
+// healthServer implements grpc.health.v1.Health. The empty service name
+// stands for the whole server and the other names for the checkers.
+type healthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	h *health.Health
+}
+
+func (s *healthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	st, ok := s.status(ctx, req.GetService())
+	if !ok {
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: st}, nil
+}
+
+func (s *healthServer) Watch(req *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	ticker := time.NewTicker(healthWatchInterval)
+	defer ticker.Stop()
+
+	last := grpc_health_v1.HealthCheckResponse_UNKNOWN
+	for {
+		st, ok := s.status(stream.Context(), req.GetService())
+		if !ok {
+			st = grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+		if st != last {
+			if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: st}); err != nil {
+				return err
+			}
+			last = st
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return status.Error(codes.Canceled, "stream has ended")
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *healthServer) status(ctx context.Context, service string) (grpc_health_v1.HealthCheckResponse_ServingStatus, bool) {
+	var st string
+	if service == "" {
+		st = s.h.Ready(ctx).Status
+	} else {
+		var ok bool
+		if st, ok = s.h.Check(ctx, service); !ok {
+			return grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN, false
+		}
+	}
+
+	if st == api.HealthServing {
+		return grpc_health_v1.HealthCheckResponse_SERVING, true
+	}
+	return grpc_health_v1.HealthCheckResponse_NOT_SERVING, true
+}
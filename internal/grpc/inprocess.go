@@ -0,0 +1,89 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/netutil"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+var errRunning = errors.New("the in-process conn must be made before the grpc server runs")
+
+// InProcessConn returns a client conn to this server through an in-memory
+// listener, which is served along with the listener of Run. It fails once
+// Run is called, since the in-memory listener would never be served.
+func (s *Server) InProcessConn() (*grpc.ClientConn, error) {
+	s.inProcessMux.Lock()
+	defer s.inProcessMux.Unlock()
+
+	if s.running {
+		return nil, errRunning
+	}
+	if s.inProcessLn == nil {
+		s.inProcessLn = netutil.ListenPipe()
+		s.inProcessConn, s.inProcessErr = grpc.Dial("inprocess",
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return s.inProcessLn.DialContext(ctx)
+			}))
+	}
+	return s.inProcessConn, s.inProcessErr
+}
+
+func (s *Server) closeInProcessConn() {
+	s.inProcessMux.Lock()
+	defer s.inProcessMux.Unlock()
+
+	if s.inProcessConn != nil {
+		_ = s.inProcessConn.Close()
+	}
+	if s.inProcessLn != nil {
+		_ = s.inProcessLn.Close()
+	}
+}
+
+// gatewayPeerKey is the metadata of a call forwarded by the grpc-gateway,
+// which refers to the http client kept by ForwardPeer.
+const gatewayPeerKey = "x-gorpc-gateway-peer"
+
+type gatewayPeer struct {
+	clientIP string
+	identity *api.PeerIdentity
+}
+
+// ForwardPeer keeps the client ip and the peer identity of an http request
+// until ctx, the context of the request, is done. The returned metadata is
+// added to the calls forwarded by the grpc-gateway, so that they see the
+// http client instead of the in-process conn.
+func (s *Server) ForwardPeer(ctx context.Context, clientIP string, identity *api.PeerIdentity) metadata.MD {
+	id := uuid.New().String()
+	s.gatewayPeers.Store(id, &gatewayPeer{clientIP: clientIP, identity: identity})
+	context.AfterFunc(ctx, func() {
+		s.gatewayPeers.Delete(id)
+	})
+	return metadata.Pairs(gatewayPeerKey, id)
+}
+
+// gatewayPeerOf returns the http client of a call forwarded by the grpc-gateway.
+// The metadata is only trusted on the in-process conn.
+func (s *Server) gatewayPeerOf(c context.Context) *gatewayPeer {
+	if pr, ok := peer.FromContext(c); !ok || pr.Addr != (netutil.PipeAddr{}) {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(c)
+	ids := md.Get(gatewayPeerKey)
+	if len(ids) == 0 {
+		return nil
+	}
+	if gw, ok := s.gatewayPeers.Load(ids[0]); ok {
+		return gw.(*gatewayPeer)
+	}
+	return nil
+}
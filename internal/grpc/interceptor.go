@@ -0,0 +1,106 @@
+package grpc
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/interceptor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+type contextKey struct{}
+
+// FromContext returns the Context stored by the interceptors of the server,
+// or a new one if c is not the context of a call.
+func FromContext(c context.Context) *Context {
+	if gCtx, ok := c.Value(contextKey{}).(*Context); ok {
+		return gCtx
+	}
+	return NewContext(c)
+}
+
+// beginCall creates the Context of a call and stores it in c, so that the
+// handlers get the same one by FromContext.
+func (s *Server) beginCall(c context.Context, method string, req interface{}) (context.Context, *Context) {
+	reqSize := 0
+	if m, ok := req.(proto.Message); ok {
+		reqSize = proto.Size(m)
+	}
+	s.processing.BeginRequest(method, reqSize)
+
+	gCtx := newContext(c, method, s.gatewayPeerOf(c))
+	gCtx.Processing = &s.processing
+	c = context.WithValue(c, contextKey{}, gCtx)
+	gCtx.ctx = c
+	return c, gCtx
+}
+
+// authenticate verifies the credentials in the metadata of c and keeps the principal in gCtx.
+func authenticate(opt *api.AuthOption, c context.Context, gCtx *Context) error {
+	md, _ := metadata.FromIncomingContext(c)
+	header := make(http.Header, len(md))
+	for k, vs := range md {
+		for _, v := range vs {
+			header.Add(k, v)
+		}
+	}
+
+	p, err := interceptor.Authenticate(opt, header)
+	if p != nil {
+		gCtx.WithValue(api.PrincipalKey, p)
+	}
+	if err != nil {
+		return interceptor.Denied(gCtx.Name, err)
+	}
+	return nil
+}
+
+func (s *Server) unaryInterceptor(opt *api.AuthOption, i api.Interceptor) grpc.UnaryServerInterceptor {
+	return func(c context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		c, gCtx := s.beginCall(c, info.FullMethod, req)
+		if err := authenticate(opt, c, gCtx); err != nil {
+			err = interceptor.GrpcError(err)
+			gCtx.EndRequest(interceptor.Code(api.TransportGrpc, err))
+			return nil, err
+		}
+
+		callInfo := &api.CallInfo{Transport: api.TransportGrpc, Method: info.FullMethod, Request: req}
+		resp, err := interceptor.Invoke(i, gCtx, callInfo, func(api.Context) (interface{}, error) {
+			return handler(c, req)
+		})
+		err = interceptor.GrpcError(err)
+		gCtx.EndRequest(interceptor.Code(api.TransportGrpc, err))
+		return resp, err
+	}
+}
+
+func (s *Server) streamInterceptor(opt *api.AuthOption, i api.Interceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		c, gCtx := s.beginCall(ss.Context(), info.FullMethod, nil)
+		if err := authenticate(opt, c, gCtx); err != nil {
+			err = interceptor.GrpcError(err)
+			gCtx.EndRequest(interceptor.Code(api.TransportGrpc, err))
+			return err
+		}
+
+		callInfo := &api.CallInfo{Transport: api.TransportGrpc, Method: info.FullMethod}
+		_, err := interceptor.Invoke(i, gCtx, callInfo, func(api.Context) (interface{}, error) {
+			return nil, handler(srv, &serverStream{ServerStream: ss, ctx: c})
+		})
+		err = interceptor.GrpcError(err)
+		gCtx.EndRequest(interceptor.Code(api.TransportGrpc, err))
+		return err
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -1,17 +1,97 @@
 package grpc
 
 import (
-	"google.golang.org/grpc"
+	"context"
 	"net"
+	"net/http"
+	"sync"
+
+	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/ctx"
+	"github.com/BabySid/gorpc/internal/health"
+	"github.com/BabySid/gorpc/internal/netutil"
+	"google.golang.org/grpc"
+	channelzpb "google.golang.org/grpc/channelz/grpc_channelz_v1"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 type Server struct {
-	gServer *grpc.Server
+	gServer  *grpc.Server
+	channelz channelzpb.ChannelzServer
+
+	// processing counts the calls of this server
+	processing ctx.Processing
+
+	inProcessMux  sync.Mutex
+	running       bool
+	inProcessLn   *netutil.PipeListener
+	inProcessConn *grpc.ClientConn
+	inProcessErr  error
+	// gatewayPeers are the http clients of the calls forwarded by the grpc-gateway
+	gatewayPeers sync.Map
 }
 
-func NewServer() *Server {
+// NewServer calls i around the handlers, which is shared with the http server.
+func NewServer(option api.ServerOption, i api.Interceptor) *Server {
+	s := &Server{}
 	var opts []grpc.ServerOption
-	return &Server{gServer: grpc.NewServer(opts...)}
+	if option.TLS != nil {
+		opts = append(opts, grpc.Creds(&tlsInfoCreds{}))
+	}
+	// the api.Context of every call is created before the grpc interceptors of the user
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor(option.Auth, i)),
+		grpc.ChainStreamInterceptor(s.streamInterceptor(option.Auth, i)))
+	if option.GrpcOpt != nil {
+		opts = append(opts, serverOptions(option.GrpcOpt)...)
+	}
+
+	s.gServer = grpc.NewServer(opts...)
+	if option.EnableReflection {
+		reflection.Register(s.gServer)
+	}
+	if option.EnableChannelz {
+		s.channelz = registerChannelz(s.gServer)
+	}
+	return s
+}
+
+func serverOptions(o *api.GrpcOption) []grpc.ServerOption {
+	var opts []grpc.ServerOption
+	if len(o.UnaryInterceptors) > 0 {
+		opts = append(opts, grpc.ChainUnaryInterceptor(o.UnaryInterceptors...))
+	}
+	if len(o.StreamInterceptors) > 0 {
+		opts = append(opts, grpc.ChainStreamInterceptor(o.StreamInterceptors...))
+	}
+	if o.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(o.MaxRecvMsgSize))
+	}
+	if o.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(o.MaxSendMsgSize))
+	}
+	if o.KeepaliveParams != nil {
+		opts = append(opts, grpc.KeepaliveParams(*o.KeepaliveParams))
+	}
+	if o.KeepaliveEnforcement != nil {
+		opts = append(opts, grpc.KeepaliveEnforcementPolicy(*o.KeepaliveEnforcement))
+	}
+	if o.ConnectionTimeout > 0 {
+		opts = append(opts, grpc.ConnectionTimeout(o.ConnectionTimeout))
+	}
+	if o.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(o.MaxConcurrentStreams))
+	}
+	for _, h := range o.StatsHandlers {
+		opts = append(opts, grpc.StatsHandler(h))
+	}
+	return append(opts, o.ServerOptions...)
+}
+
+// Handler serves grpc over the http2 requests of net/http.
+func (s *Server) Handler() http.Handler {
+	return s.gServer
 }
 
 func (s *Server) RegisterGRPC(desc *grpc.ServiceDesc, impl interface{}) error {
@@ -19,6 +99,55 @@ func (s *Server) RegisterGRPC(desc *grpc.ServiceDesc, impl interface{}) error {
 	return nil
 }
 
+// RegisterHealth registers grpc.health.v1.Health backed by h,
+// unless the user has registered an implementation of it.
+func (s *Server) RegisterHealth(h *health.Health) {
+	if _, ok := s.gServer.GetServiceInfo()[grpc_health_v1.Health_ServiceDesc.ServiceName]; ok {
+		return
+	}
+	grpc_health_v1.RegisterHealthServer(s.gServer, &healthServer{h: h})
+}
+
 func (s *Server) Run(ln net.Listener) error {
+	s.inProcessMux.Lock()
+	s.running = true
+	inProcessLn := s.inProcessLn
+	s.inProcessMux.Unlock()
+
+	if inProcessLn != nil {
+		go func() {
+			_ = s.gServer.Serve(inProcessLn)
+		}()
+	}
 	return s.gServer.Serve(ln)
 }
+
+// Shutdown stops the server gracefully and waits for the processing calls.
+// Pending RPCs are cancelled if they do not finish before ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	defer s.closeInProcessConn()
+
+	done := make(chan struct{})
+	go func() {
+		s.gServer.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return s.processing.Wait(ctx)
+	case <-ctx.Done():
+		s.gServer.Stop()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Stop closes the listeners and the connections at once, and then waits for
+// the cancelled calls to return until ctx is done.
+func (s *Server) Stop(ctx context.Context) error {
+	defer s.closeInProcessConn()
+
+	s.gServer.Stop()
+	return s.processing.Wait(ctx)
+}
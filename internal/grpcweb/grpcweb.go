@@ -0,0 +1,99 @@
+package grpcweb
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/BabySid/gorpc/internal/cors"
+)
+
+const (
+	contentTypeGrpc        = "application/grpc"
+	contentTypeGrpcWeb     = "application/grpc-web"
+	contentTypeGrpcWebText = "application/grpc-web-text"
+
+	exposeHeaders = "grpc-status,grpc-message,grpc-status-details-bin"
+)
+
+// Handler translates grpc-web requests to grpc over http2 and serves them
+// by the grpc server, so browsers can call the grpc services without a proxy.
+type Handler struct {
+	grpc http.Handler
+	// cors allows the cross origin calls, which are refused if it is nil
+	cors *cors.Cors
+}
+
+func New(grpc http.Handler, c *cors.Cors) *Handler {
+	return &Handler{grpc: grpc, cors: c}
+}
+
+// Match reports whether r is a grpc-web request or the cors preflight of one.
+func Match(r *http.Request) bool {
+	return isGrpcWeb(r) || isPreflight(r)
+}
+
+func isGrpcWeb(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeGrpcWeb)
+}
+
+// isPreflight relies on the x-grpc-web header sent by every grpc-web client.
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get("Access-Control-Request-Method") != "" &&
+		strings.Contains(strings.ToLower(r.Header.Get("Access-Control-Request-Headers")), "x-grpc-web")
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	allowed := origin != "" && h.cors != nil && h.cors.AllowOrigin(origin)
+
+	if isPreflight(r) {
+		if !allowed {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		hd := w.Header()
+		h.cors.SetOrigin(hd, origin)
+		hd.Set("Access-Control-Allow-Methods", http.MethodPost)
+		hd.Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
+		h.cors.SetMaxAge(hd)
+		hd.Add("Vary", "Origin")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if allowed {
+		hd := w.Header()
+		h.cors.SetOrigin(hd, origin)
+		hd.Set("Access-Control-Expose-Headers", exposeHeaders)
+		hd.Add("Vary", "Origin")
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	base := contentTypeGrpcWeb
+	text := strings.HasPrefix(contentType, contentTypeGrpcWebText)
+	if text {
+		base = contentTypeGrpcWebText
+	}
+	// the subtype like +proto is kept
+	subtype := strings.TrimPrefix(contentType, base)
+	if i := strings.IndexByte(subtype, ';'); i >= 0 {
+		subtype = subtype[:i]
+	}
+
+	req := r.Clone(r.Context())
+	req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2.0"
+	req.Header.Set("Content-Type", contentTypeGrpc+subtype)
+	req.Header.Del("Content-Length")
+	if text {
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{newBase64Reader(r.Body), r.Body}
+	}
+
+	rw := newResponseWriter(w, base+subtype, text)
+	h.grpc.ServeHTTP(rw, req)
+	rw.finish()
+}
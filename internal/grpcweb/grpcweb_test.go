@@ -0,0 +1,51 @@
+package grpcweb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/cors"
+)
+
+func TestPreflight(t *testing.T) {
+	tests := []struct {
+		name       string
+		cors       *cors.Cors
+		origin     string
+		wantCode   int
+		wantOrigin string
+		wantMaxAge string
+	}{
+		{name: "no cors", origin: "https://app.com", wantCode: http.StatusForbidden},
+		{name: "not allowed", cors: cors.New(&api.CorsOption{AllowedOrigins: []string{"https://app.com"}}),
+			origin: "https://evil.com", wantCode: http.StatusForbidden},
+		{name: "no max age", cors: cors.New(&api.CorsOption{AllowedOrigins: []string{"https://app.com"}}),
+			origin: "https://app.com", wantCode: http.StatusNoContent, wantOrigin: "https://app.com"},
+		{name: "max age", cors: cors.New(&api.CorsOption{AllowedOrigins: []string{"*"}, MaxAge: 10 * time.Minute}),
+			origin: "https://app.com", wantCode: http.StatusNoContent, wantOrigin: "*", wantMaxAge: "600"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodOptions, "/pkg.Service/Method", nil)
+			r.Header.Set("Origin", tt.origin)
+			r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			r.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+			w := httptest.NewRecorder()
+
+			New(http.NotFoundHandler(), tt.cors).ServeHTTP(w, r)
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Max-Age"); got != tt.wantMaxAge {
+				t.Errorf("Access-Control-Max-Age = %q, want %q", got, tt.wantMaxAge)
+			}
+		})
+	}
+}
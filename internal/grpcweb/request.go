@@ -0,0 +1,73 @@
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+)
+
+// base64Reader decodes the body of grpc-web-text, which is a series of base64
+// chunks, one for each write of the client. Every chunk may end with padding,
+// so the body is decoded chunk by chunk instead of as one base64 stream.
+type base64Reader struct {
+	r   io.Reader
+	buf []byte
+	// in is the base64 read but not decoded yet, without line breaks
+	in []byte
+	// out is decoded but not read yet
+	out []byte
+	err error
+}
+
+func newBase64Reader(r io.Reader) *base64Reader {
+	return &base64Reader{r: r, buf: make([]byte, 4096)}
+}
+
+func (b *base64Reader) Read(p []byte) (int, error) {
+	for len(b.out) == 0 {
+		if b.err != nil {
+			if b.err == io.EOF && len(b.in) > 0 {
+				return 0, io.ErrUnexpectedEOF
+			}
+			return 0, b.err
+		}
+
+		n, err := b.r.Read(b.buf)
+		for _, c := range b.buf[:n] {
+			if c != '\r' && c != '\n' {
+				b.in = append(b.in, c)
+			}
+		}
+		b.err = err
+		if err := b.decode(); err != nil {
+			b.err = err
+		}
+	}
+
+	n := copy(p, b.out)
+	b.out = b.out[n:]
+	return n, nil
+}
+
+// decode decodes the complete quanta of 4 characters in b.in. The quantum
+// with padding ends a chunk, and the next one starts after it.
+func (b *base64Reader) decode() error {
+	n := len(b.in) / 4 * 4
+	in := b.in[:n]
+	for len(in) > 0 {
+		end := len(in)
+		if i := bytes.IndexByte(in, '='); i >= 0 {
+			end = (i/4 + 1) * 4
+		}
+
+		buf := make([]byte, base64.StdEncoding.DecodedLen(end))
+		m, err := base64.StdEncoding.Decode(buf, in[:end])
+		if err != nil {
+			return err
+		}
+		b.out = append(b.out, buf[:m]...)
+		in = in[end:]
+	}
+	b.in = append(b.in[:0], b.in[n:]...)
+	return nil
+}
@@ -0,0 +1,42 @@
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestBase64Reader(t *testing.T) {
+	enc := base64.StdEncoding.EncodeToString
+	tests := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{name: "empty", body: "", want: ""},
+		{name: "one chunk", body: enc([]byte("hello")), want: "hello"},
+		{name: "no padding", body: enc([]byte("abc")), want: "abc"},
+		{name: "padded chunks", body: enc([]byte("a")) + enc([]byte("bc")) + enc([]byte("def")), want: "abcdef"},
+		{name: "line breaks", body: enc([]byte("hello"))[:4] + "\r\n" + enc([]byte("hello"))[4:] + "\n", want: "hello"},
+		{name: "truncated", body: enc([]byte("hello"))[:6], wantErr: true},
+		{name: "invalid", body: "a*==", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// one byte per read, so that the quanta are split across reads
+			r := newBase64Reader(iotest.OneByteReader(strings.NewReader(tt.body)))
+			got, err := io.ReadAll(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(got, []byte(tt.want)) {
+				t.Errorf("ReadAll() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
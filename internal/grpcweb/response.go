@@ -0,0 +1,152 @@
+package grpcweb
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http2"
+)
+
+// trailerFlag marks the frame carrying the trailers in the body.
+const trailerFlag = 0x80
+
+// responseWriter moves the http2 trailers set by the grpc server into the
+// body as grpc-web requires, and encodes the body by base64 for the text mode.
+type responseWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	contentType string
+
+	out  io.Writer
+	text *base64Writer
+
+	wroteHeader bool
+}
+
+func newResponseWriter(w http.ResponseWriter, contentType string, text bool) *responseWriter {
+	rw := &responseWriter{
+		w:           w,
+		header:      make(http.Header),
+		contentType: contentType,
+		out:         w,
+	}
+	if text {
+		rw.text = &base64Writer{w: w}
+		rw.out = rw.text
+	}
+	return rw
+}
+
+func (rw *responseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+
+	trailers := rw.trailerKeys()
+	hd := rw.w.Header()
+	for k, vv := range rw.header {
+		if k == "Trailer" || trailers[k] || strings.HasPrefix(k, http2.TrailerPrefix) {
+			continue
+		}
+		hd[k] = vv
+	}
+	hd.Set("Content-Type", rw.contentType)
+	hd.Del("Content-Length")
+	rw.w.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	rw.WriteHeader(http.StatusOK)
+	return rw.out.Write(p)
+}
+
+// Flush ends the base64 chunk in the text mode, so that the frames written
+// so far are sent whole.
+func (rw *responseWriter) Flush() {
+	rw.WriteHeader(http.StatusOK)
+	if rw.text != nil {
+		_ = rw.text.Flush()
+	}
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// trailerKeys are the keys declared by the Trailer header.
+func (rw *responseWriter) trailerKeys() map[string]bool {
+	keys := make(map[string]bool)
+	for _, v := range rw.header.Values("Trailer") {
+		for _, k := range strings.Split(v, ",") {
+			keys[http.CanonicalHeaderKey(strings.TrimSpace(k))] = true
+		}
+	}
+	return keys
+}
+
+// finish writes the trailers as the last frame of the body.
+func (rw *responseWriter) finish() {
+	rw.WriteHeader(http.StatusOK)
+
+	trailers := rw.trailerKeys()
+	var block strings.Builder
+	for k, vv := range rw.header {
+		if !trailers[k] && !strings.HasPrefix(k, http2.TrailerPrefix) {
+			continue
+		}
+		name := strings.ToLower(strings.TrimPrefix(k, http2.TrailerPrefix))
+		for _, v := range vv {
+			block.WriteString(name + ": " + v + "\r\n")
+		}
+	}
+
+	if block.Len() > 0 {
+		frame := make([]byte, 5+block.Len())
+		frame[0] = trailerFlag
+		binary.BigEndian.PutUint32(frame[1:5], uint32(block.Len()))
+		copy(frame[5:], block.String())
+		_, _ = rw.out.Write(frame)
+	}
+	if rw.text != nil {
+		_ = rw.text.Flush()
+	}
+}
+
+// base64Writer encodes by base64 in groups of 3 bytes. The rest is written with
+// padding by Flush, which ends a chunk, and the clients decode the body chunk by
+// chunk like base64Reader.
+type base64Writer struct {
+	w    io.Writer
+	rest []byte
+}
+
+func (b *base64Writer) Write(p []byte) (int, error) {
+	data := append(b.rest, p...)
+	n := len(data) / 3 * 3
+	if n > 0 {
+		buf := make([]byte, base64.StdEncoding.EncodedLen(n))
+		base64.StdEncoding.Encode(buf, data[:n])
+		if _, err := b.w.Write(buf); err != nil {
+			return 0, err
+		}
+	}
+	b.rest = append([]byte(nil), data[n:]...)
+	return len(p), nil
+}
+
+// Flush writes the rest bytes with padding.
+func (b *base64Writer) Flush() error {
+	if len(b.rest) == 0 {
+		return nil
+	}
+	_, err := b.w.Write([]byte(base64.StdEncoding.EncodeToString(b.rest)))
+	b.rest = nil
+	return err
+}
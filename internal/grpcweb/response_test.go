@@ -0,0 +1,83 @@
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// chunkRecorder keeps the body written before each flush as a chunk.
+type chunkRecorder struct {
+	*httptest.ResponseRecorder
+	chunks []string
+	off    int
+}
+
+func (r *chunkRecorder) Flush() {
+	body := r.Body.String()
+	r.chunks = append(r.chunks, body[r.off:])
+	r.off = len(body)
+}
+
+func frame(payload []byte) []byte {
+	f := make([]byte, 5+len(payload))
+	binary.BigEndian.PutUint32(f[1:5], uint32(len(payload)))
+	copy(f[5:], payload)
+	return f
+}
+
+func TestStreamText(t *testing.T) {
+	tests := []struct {
+		name  string
+		sizes []int
+	}{
+		{name: "empty message", sizes: []int{0}},
+		{name: "one byte left", sizes: []int{2}},
+		{name: "two bytes left", sizes: []int{3}},
+		{name: "no bytes left", sizes: []int{1}},
+		{name: "stream", sizes: []int{2, 3, 1, 10, 0, 7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &chunkRecorder{ResponseRecorder: httptest.NewRecorder()}
+			rw := newResponseWriter(rec, contentTypeGrpcWebText, true)
+
+			var want []byte
+			for i, size := range tt.sizes {
+				f := frame(bytes.Repeat([]byte{byte('a' + i)}, size))
+				want = append(want, f...)
+				if _, err := rw.Write(f); err != nil {
+					t.Fatal(err)
+				}
+				rw.Flush()
+
+				// every flushed chunk is a whole frame
+				got, err := base64.StdEncoding.DecodeString(rec.chunks[i])
+				if err != nil {
+					t.Fatalf("chunk %d %q: %v", i, rec.chunks[i], err)
+				}
+				if !bytes.Equal(got, f) {
+					t.Errorf("chunk %d = %x, want %x", i, got, f)
+				}
+			}
+			rw.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+			rw.finish()
+
+			// the whole body is decoded like the request of a client
+			got, err := io.ReadAll(newBase64Reader(rec.Body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			trailer := frame([]byte("grpc-status: 0\r\n"))
+			trailer[0] = trailerFlag
+			if want = append(want, trailer...); !bytes.Equal(got, want) {
+				t.Errorf("body = %x, want %x", got, want)
+			}
+		})
+	}
+}
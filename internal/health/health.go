@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BabySid/gorpc/api"
+)
+
+const checkTimeout = 5 * time.Second
+
+// Health holds the named checkers of the server. The server is ready when
+// it is serving and all the checkers pass.
+type Health struct {
+	mux      sync.RWMutex
+	checkers map[string]api.HealthChecker
+
+	serving atomic.Bool
+}
+
+func New() *Health {
+	return &Health{checkers: make(map[string]api.HealthChecker)}
+}
+
+func (h *Health) Register(name string, checker api.HealthChecker) error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	if _, ok := h.checkers[name]; ok {
+		return fmt.Errorf("health checker already defined: %s", name)
+	}
+	h.checkers[name] = checker
+	return nil
+}
+
+// SetServing is turned on once the server is running
+// and turned off as soon as it begins to shut down.
+func (h *Health) SetServing(serving bool) {
+	h.serving.Store(serving)
+}
+
+func (h *Health) Serving() bool {
+	return h.serving.Load()
+}
+
+// Ready runs all the checkers concurrently.
+func (h *Health) Ready(ctx context.Context) *api.HealthResult {
+	h.mux.RLock()
+	checkers := make(map[string]api.HealthChecker, len(h.checkers))
+	for name, checker := range h.checkers {
+		checkers[name] = checker
+	}
+	h.mux.RUnlock()
+
+	res := &api.HealthResult{Status: api.HealthServing, Checks: make(map[string]string, len(checkers))}
+	if !h.Serving() {
+		res.Status = api.HealthNotServing
+	}
+
+	var wg sync.WaitGroup
+	var resMux sync.Mutex
+	for name, checker := range checkers {
+		wg.Add(1)
+		go func(name string, checker api.HealthChecker) {
+			defer wg.Done()
+			err := run(ctx, checker)
+
+			resMux.Lock()
+			defer resMux.Unlock()
+			res.Checks[name] = "ok"
+			if err != nil {
+				res.Checks[name] = err.Error()
+				res.Status = api.HealthNotServing
+			}
+		}(name, checker)
+	}
+	wg.Wait()
+	return res
+}
+
+// Check runs the checker of name. The bool result is false if there is no such checker.
+func (h *Health) Check(ctx context.Context, name string) (string, bool) {
+	h.mux.RLock()
+	checker, ok := h.checkers[name]
+	h.mux.RUnlock()
+	if !ok {
+		return "", false
+	}
+
+	if !h.Serving() || run(ctx, checker) != nil {
+		return api.HealthNotServing, true
+	}
+	return api.HealthServing, true
+}
+
+func run(ctx context.Context, checker api.HealthChecker) error {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	return checker(ctx)
+}
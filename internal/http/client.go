@@ -2,16 +2,22 @@ package http
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/BabySid/gobase"
 	"github.com/BabySid/gorpc/api"
 	"github.com/BabySid/gorpc/internal/jsonrpc"
+	"github.com/BabySid/gorpc/internal/netutil"
+	"golang.org/x/net/http2"
 )
 
 var ErrNoResult = errors.New("no result in JSON-RPC response")
@@ -89,7 +95,7 @@ func (c *Client) RawCallHttp(method string, path string, body interface{}) (*api
 }
 
 func Dial(rawUrl string, opt api.ClientOption) (*Client, error) {
-	_, err := url.Parse(rawUrl)
+	u, err := url.Parse(rawUrl)
 	if err != nil {
 		return nil, err
 	}
@@ -102,9 +108,34 @@ func Dial(rawUrl string, opt api.ClientOption) (*Client, error) {
 		}
 	}
 
+	var dial func(ctx context.Context, network, addr string) (net.Conn, error)
+	if u.Scheme == "http+unix" {
+		var sockPath string
+		sockPath, rawUrl, err = netutil.SplitUnixURL(rawUrl)
+		if err != nil {
+			return nil, err
+		}
+		dial = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sockPath)
+		}
+	}
+
+	httpHandle := new(http.Client)
+	if opt.H2C && u.Scheme != "https" {
+		httpHandle.Transport = h2cTransport(dial)
+	} else if opt.TLSConfig != nil || dial != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = opt.TLSConfig
+		if dial != nil {
+			transport.Proxy = nil
+			transport.DialContext = dial
+		}
+		httpHandle.Transport = transport
+	}
+
 	c := &Client{
 		rawUrl:     rawUrl,
-		httpHandle: new(http.Client),
+		httpHandle: httpHandle,
 		jsonRpcCli: nil,
 		header:     headers,
 		opt:        opt,
@@ -117,6 +148,25 @@ func Dial(rawUrl string, opt api.ClientOption) (*Client, error) {
 	return c, nil
 }
 
+// h2cDialTimeout bounds the dials of h2c like those of http.DefaultTransport,
+// since http2.Transport gives no context to DialTLS.
+const h2cDialTimeout = 30 * time.Second
+
+// h2cTransport speaks http2 with prior knowledge over cleartext connections.
+func h2cTransport(dial func(ctx context.Context, network, addr string) (net.Conn, error)) *http2.Transport {
+	if dial == nil {
+		dial = (&net.Dialer{KeepAlive: 30 * time.Second}).DialContext
+	}
+	return &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), h2cDialTimeout)
+			defer cancel()
+			return dial(ctx, network, addr)
+		},
+	}
+}
+
 func (c *Client) checkHttpError(resp *api.HttpResponse) error {
 	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
 		return errors.New(fmt.Sprintf("%d:%s", resp.StatusCode, string(resp.Body)))
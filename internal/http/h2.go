@@ -0,0 +1,72 @@
+package http
+
+import (
+	"bufio"
+	"net"
+
+	"golang.org/x/net/http2"
+)
+
+const http2FrameHeaderLen = 9
+
+// h2Listener accepts the http2 connections which were not matched as grpc.
+// While sniffing for grpc, cmux answers the client preface with its own
+// SETTINGS frame. The client acknowledges it, but the http2 server never sent
+// that frame and would hang up on the unexpected ACK, so it is dropped here.
+type h2Listener struct {
+	net.Listener
+}
+
+func (l *h2Listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &settingsAckConn{
+		Conn:   c,
+		r:      bufio.NewReader(c),
+		remain: len(http2.ClientPreface),
+	}, nil
+}
+
+type settingsAckConn struct {
+	net.Conn
+	r *bufio.Reader
+
+	// remain is the number of bytes left of the frame being passed through
+	remain  int
+	dropped bool
+}
+
+func (c *settingsAckConn) NetConn() net.Conn {
+	return c.Conn
+}
+
+func (c *settingsAckConn) Read(p []byte) (int, error) {
+	if c.dropped {
+		return c.r.Read(p)
+	}
+
+	for c.remain == 0 {
+		hdr, err := c.r.Peek(http2FrameHeaderLen)
+		if err != nil {
+			return 0, err
+		}
+		length := int(hdr[0])<<16 | int(hdr[1])<<8 | int(hdr[2])
+		if http2.FrameType(hdr[3]) == http2.FrameSettings && http2.Flags(hdr[4]).Has(http2.FlagSettingsAck) {
+			if _, err = c.r.Discard(http2FrameHeaderLen + length); err != nil {
+				return 0, err
+			}
+			c.dropped = true
+			return c.r.Read(p)
+		}
+		c.remain = http2FrameHeaderLen + length
+	}
+
+	if len(p) > c.remain {
+		p = p[:c.remain]
+	}
+	n, err := c.r.Read(p)
+	c.remain -= n
+	return n, err
+}
@@ -1,14 +1,15 @@
 package http
 
 import (
+	"io"
 	"log/slog"
 	"time"
 
 	"github.com/BabySid/gobase"
 	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/cert"
 	"github.com/BabySid/gorpc/internal/ctx"
 	"github.com/BabySid/gorpc/internal/log"
-	"github.com/BabySid/gorpc/metrics"
 	"github.com/gin-gonic/gin"
 )
 
@@ -24,17 +25,23 @@ func (ctx *Context) ClientIP() string {
 	return ctx.ctx.ClientIP()
 }
 
-func newHttpContext(name string, id interface{}, reqSize int, c *gin.Context) *Context {
-	metrics.ProcessingRequests.WithLabelValues(metrics.GetCluster(), name).Inc()
-	metrics.RealTimeRequestBodySize.WithLabelValues(metrics.GetCluster(), name).Set(float64(reqSize))
+func (ctx *Context) PeerIdentity() *api.PeerIdentity {
+	gobase.True(ctx.ctx != nil)
+	return cert.RequestIdentity(ctx.ctx.Request)
+}
+
+func newHttpContext(p *ctx.Processing, name string, id interface{}, reqSize int, c *gin.Context) *Context {
+	p.BeginRequest(name, reqSize)
 	httpCtx := &Context{
 		ctx: c,
 		ContextAdapter: ctx.ContextAdapter{
-			Name:    name,
-			RevTime: time.Now(),
-			ID:      id,
-			KV:      make(map[string]any),
-			Logger:  nil,
+			Name:       name,
+			RevTime:    time.Now(),
+			ID:         id,
+			Ctx:        c.Request.Context(),
+			KV:         make(map[string]any),
+			Logger:     nil,
+			Processing: p,
 		},
 	}
 
@@ -45,22 +52,26 @@ func newHttpContext(name string, id interface{}, reqSize int, c *gin.Context) *C
 
 var _ api.RawHttpContext = (*RawContext)(nil)
 
+// httpContext is embedded by its alias, whose field does not clash with the method Context.
+type httpContext = Context
+
 type RawContext struct {
-	Context
+	httpContext
 }
 
-func newRawContext(name string, id interface{}, reqSize int, c *gin.Context) *RawContext {
-	metrics.ProcessingRequests.WithLabelValues(metrics.GetCluster(), name).Inc()
-	metrics.RealTimeRequestBodySize.WithLabelValues(metrics.GetCluster(), name).Set(float64(reqSize))
+func newRawContext(p *ctx.Processing, name string, id interface{}, reqSize int, c *gin.Context) *RawContext {
+	p.BeginRequest(name, reqSize)
 	rawCtx := &RawContext{
-		Context: Context{
+		httpContext: Context{
 			ctx: c,
 			ContextAdapter: ctx.ContextAdapter{
-				Name:    name,
-				RevTime: time.Now(),
-				ID:      id,
-				KV:      make(map[string]any),
-				Logger:  nil,
+				Name:       name,
+				RevTime:    time.Now(),
+				ID:         id,
+				Ctx:        c.Request.Context(),
+				KV:         make(map[string]any),
+				Logger:     nil,
+				Processing: p,
 			},
 		},
 	}
@@ -77,6 +88,10 @@ func (r *RawContext) Query(key string) string {
 	return r.ctx.Query(key)
 }
 
+func (r *RawContext) Body() io.Reader {
+	return r.ctx.Request.Body
+}
+
 func (r *RawContext) WriteData(code int, contType string, data []byte) error {
 	r.ctx.Data(code, contType, data)
 	return nil
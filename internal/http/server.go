@@ -1,19 +1,28 @@
 package http
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/BabySid/gobase"
 	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/cert"
+	"github.com/BabySid/gorpc/internal/cors"
+	"github.com/BabySid/gorpc/internal/ctx"
 	"github.com/BabySid/gorpc/internal/gin"
+	"github.com/BabySid/gorpc/internal/grpcweb"
+	"github.com/BabySid/gorpc/internal/health"
+	"github.com/BabySid/gorpc/internal/interceptor"
 	"github.com/BabySid/gorpc/internal/jsonrpc"
 	"github.com/BabySid/gorpc/internal/log"
 	"github.com/BabySid/gorpc/internal/websocket"
@@ -25,21 +34,67 @@ import (
 type Server struct {
 	opt        api.ServerOption
 	httpServer *gin.Server
+	server     *http.Server
 
-	rpcServer *jsonrpc.Server
+	// adminServer serves the inner services if AdminAddr is set
+	adminServer *gin.Server
+	admin       *http.Server
+
+	rpcServer   *jsonrpc.Server
+	health      *health.Health
+	interceptor api.Interceptor
 
 	rawWsHandle api.RawWsHandle
+	grpcWeb     *grpcweb.Handler
+	cors        *cors.Cors
+
+	wsMux      sync.Mutex
+	wsWg       sync.WaitGroup
+	wsSessions map[*websocket.Server]struct{}
+	wsClosing  bool
+
+	// processing counts the requests of this server, including its websocket messages
+	processing ctx.Processing
 }
 
-func NewServer(option api.ServerOption) *Server {
+// NewServer calls i around the handlers, which is shared with the grpc server.
+func NewServer(option api.ServerOption, hc *health.Health, i api.Interceptor) *Server {
 	s := &Server{
-		opt:        option,
-		httpServer: gin.NewServer(),
-		rpcServer:  nil,
+		opt:         option,
+		httpServer:  gin.NewServer(),
+		rpcServer:   nil,
+		health:      hc,
+		interceptor: i,
+		wsSessions:  make(map[*websocket.Server]struct{}),
+	}
+	// http2 reaches the http server as prior knowledge h2c once tls is terminated
+	s.httpServer.UseH2C = s.opt.TLS != nil || s.opt.EnableH2C
+	s.server = &http.Server{
+		Handler:     s.httpServer.Handler(),
+		ConnContext: cert.WithConn,
+	}
+
+	if s.opt.EnableInnerService && s.opt.AdminAddr != "" {
+		s.adminServer = gin.NewServer()
+		s.admin = &http.Server{Handler: s.adminServer}
+	}
+
+	if s.opt.GrpcWebOpt != nil {
+		// it also sees the requests matching no route
+		s.httpServer.Use(s.processGrpcWeb)
+	}
+	if s.opt.Cors != nil {
+		s.cors = cors.New(s.opt.Cors)
+		// it also answers the preflights matching no route
+		s.httpServer.Use(s.processCors)
 	}
 
 	if s.opt.JsonRpcOpt != nil {
-		s.rpcServer = jsonrpc.NewServer(jsonrpc.Option{CodeType: s.opt.JsonRpcOpt.Codec})
+		s.rpcServer = jsonrpc.NewServer(jsonrpc.Option{
+			CodeType:    s.opt.JsonRpcOpt.Codec,
+			Interceptor: s.interceptor,
+			Timeout:     s.opt.JsonRpcOpt.Timeout,
+		})
 	}
 
 	s.setUpBuiltInService()
@@ -50,17 +105,31 @@ func (s *Server) setUpBuiltInService() {
 	s.httpServer.POST(api.BuiltInPathJsonRPC, s.processJsonRpcWithHttp)
 	s.httpServer.GET(api.BuiltInPathWsJsonRPC, s.processJsonRpcWithWS)
 	s.httpServer.GET(api.BuiltInPathRawWS, s.processRawWS)
+	s.httpServer.GET(api.BuiltInPathHealthLive, s.processLive)
+	s.httpServer.GET(api.BuiltInPathHealthReady, s.processReady)
 
 	if s.opt.EnableInnerService {
-		s.httpServer.GET(api.BuiltInPathMetrics, g.WrapH(promhttp.Handler()))
+		inner := s.httpServer
+		if s.adminServer != nil {
+			inner = s.adminServer
+		}
+
+		inner.GET(api.BuiltInPathMetrics, g.WrapH(promhttp.Handler()))
 
 		path, err := filepath.Abs(filepath.Dir(os.Args[0]))
 		gobase.True(err == nil)
 		dir := http.Dir(path + "/..")
 		log.DefaultLog.Info("init static fs", slog.Any("path", dir))
-		s.httpServer.StaticFS(api.BuiltInPathDIR, dir)
+		inner.StaticFS(api.BuiltInPathDIR, dir)
 
 		appName := filepath.Base(os.Args[0])
+		channelzRow := ""
+		if s.opt.EnableChannelz {
+			channelzRow = fmt.Sprintf(`
+  <tr>
+	<td><a href="/%s">grpc channelz of %s</a></td>
+  </tr>`, api.BuiltInPathChannelz, appName)
+		}
 		indexHtml := fmt.Sprintf(`
 <h2>WelCome to %s</h2>
 <table border="1">
@@ -72,11 +141,11 @@ func (s *Server) setUpBuiltInService() {
   </tr>
   <tr>
 	<td><a href="/%s">metrics of %s</a></td>
-  </tr>
+  </tr>%s
 </table>
-`, appName, api.BuiltInPathDIR, appName, api.BuiltInPathMetrics, appName)
+`, appName, api.BuiltInPathDIR, appName, api.BuiltInPathMetrics, appName, channelzRow)
 
-		s.httpServer.GET("/", func(ctx *g.Context) {
+		inner.GET("/", func(ctx *g.Context) {
 			ctx.Header("Content-Type", "text/html; charset=utf-8")
 
 			ctx.String(http.StatusOK, indexHtml)
@@ -84,8 +153,59 @@ func (s *Server) setUpBuiltInService() {
 	}
 }
 
-func (s *Server) RegisterJsonRPC(name string, receiver interface{}) error {
-	return s.rpcServer.RegisterName(name, receiver)
+// RegisterChannelz serves the channelz summary page among the inner services.
+func (s *Server) RegisterChannelz(h http.Handler) {
+	if !s.opt.EnableInnerService || h == nil {
+		return
+	}
+	inner := s.httpServer
+	if s.adminServer != nil {
+		inner = s.adminServer
+	}
+	inner.GET(api.BuiltInPathChannelz, g.WrapH(h))
+}
+
+// RegisterGrpcWeb serves the grpc-web requests by the grpc server h.
+func (s *Server) RegisterGrpcWeb(h http.Handler) {
+	if s.opt.GrpcWebOpt == nil {
+		return
+	}
+	c := s.cors
+	if origins := s.opt.GrpcWebOpt.AllowedOrigins; len(origins) > 0 {
+		// the rest of the cors option still applies
+		var opt api.CorsOption
+		if s.opt.Cors != nil {
+			opt = *s.opt.Cors
+		}
+		opt.AllowedOrigins = origins
+		c = cors.New(&opt)
+	}
+	s.grpcWeb = grpcweb.New(h, c)
+}
+
+// RegisterGateway serves the requests which match no route by h,
+// which is the grpc-gateway mux. The client ip of the requests is
+// found by GatewayClientIP.
+func (s *Server) RegisterGateway(h http.Handler) {
+	s.httpServer.NoRoute(func(c *g.Context) {
+		// gin presets 404 for NoRoute, leave the status to h
+		c.Status(http.StatusOK)
+		r := c.Request.WithContext(context.WithValue(c.Request.Context(), clientIPKey{}, c.ClientIP()))
+		h.ServeHTTP(c.Writer, r)
+	})
+}
+
+type clientIPKey struct{}
+
+// GatewayClientIP returns the client ip of a request served by the gateway,
+// which is resolved the same way as the other http routes.
+func GatewayClientIP(r *http.Request) string {
+	ip, _ := r.Context().Value(clientIPKey{}).(string)
+	return ip
+}
+
+func (s *Server) RegisterJsonRPC(name string, receiver interface{}, opts ...api.JsonRpcServiceOption) error {
+	return s.rpcServer.RegisterName(name, receiver, opts...)
 }
 
 func (s *Server) RegisterRawWs(handle api.RawWsHandle) error {
@@ -93,22 +213,32 @@ func (s *Server) RegisterRawWs(handle api.RawWsHandle) error {
 	return nil
 }
 
-func (s *Server) RegisterPath(httpMethod string, path string, handle api.RawHttpHandle) error {
+func (s *Server) RegisterPath(httpMethod string, path string, handle api.RawHttpHandle, opts ...api.RawPathOption) error {
 	if err := s.checkPath(path); err != nil {
 		return err
 	}
+	cfg := api.RawPath{MaxBodySize: s.opt.MaxBodySize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
 	switch httpMethod {
 	case http.MethodGet:
-		s.httpServer.GET(path, getHandleWrapper(handle))
+		if cfg != (api.RawPath{MaxBodySize: s.opt.MaxBodySize}) {
+			return invalidBodyOption
+		}
+		s.httpServer.GET(path, s.handleWrapper(handle, nil))
 	case http.MethodPost:
-		s.httpServer.POST(path, postHandleWrapper(handle))
+		s.httpServer.POST(path, s.handleWrapper(handle, &cfg))
 	default:
 		gobase.AssertHere()
 	}
 	return nil
 }
 
-var invalidPath = errors.New("path is invalid. conflict with builtin")
+var (
+	invalidPath       = errors.New("path is invalid. conflict with builtin")
+	invalidBodyOption = errors.New("the body options only apply to POST")
+)
 
 func (s *Server) checkPath(path string) error {
 	rootPath := ""
@@ -126,7 +256,9 @@ func (s *Server) checkPath(path string) error {
 		rootPath == api.BuiltInPathJsonRPC ||
 		rootPath == api.BuiltInPathWsJsonRPC ||
 		rootPath == api.BuiltInPathRawWS ||
-		rootPath == api.BuiltInPathDIR {
+		rootPath == api.BuiltInPathDIR ||
+		rootPath == api.BuiltInPathChannelz ||
+		rootPath == api.BuiltInPathHealth {
 		return invalidPath
 	}
 
@@ -134,44 +266,245 @@ func (s *Server) checkPath(path string) error {
 }
 
 func (s *Server) Run(ln net.Listener) error {
-	return s.httpServer.RunListener(ln)
+	return s.server.Serve(ln)
 }
 
-func (s *Server) processRawWS(c *g.Context) {
-	gobase.True(s.rawWsHandle != nil)
-	srv, err := websocket.NewServer(c, websocket.WithRawHandle(s.rawWsHandle))
+// RunH2 serves the http2 connections which are not grpc.
+func (s *Server) RunH2(ln net.Listener) error {
+	return s.server.Serve(&h2Listener{Listener: ln})
+}
+
+// RunAdmin serves the inner services on the admin listener.
+func (s *Server) RunAdmin(ln net.Listener) error {
+	gobase.True(s.admin != nil)
+	return s.admin.Serve(ln)
+}
+
+// Shutdown sends a going away frame to every websocket session, then drains
+// the http servers and waits for the websocket sessions and the processing
+// requests to end. Every step is run even if an earlier one fails, and the
+// connections and the websocket sessions still active when ctx is done are closed.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.wsMux.Lock()
+	s.wsClosing = true
+	for srv := range s.wsSessions {
+		srv.GoingAway()
+	}
+	s.wsMux.Unlock()
+
+	var errs []error
+	if s.admin != nil {
+		errs = append(errs, shutdown(ctx, s.admin))
+	}
+	errs = append(errs, shutdown(ctx, s.server))
+
+	done := make(chan struct{})
+	go func() {
+		s.wsWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.abortWsSessions()
+		errs = append(errs, fmt.Errorf("wait websocket sessions: %w", ctx.Err()))
+	}
+
+	if err := s.processing.Wait(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("wait processing requests: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes the http servers and the websocket sessions at once, and then
+// waits for the cancelled requests to return until ctx is done.
+func (s *Server) Close(ctx context.Context) error {
+	s.abortWsSessions()
+
+	var errs []error
+	if s.admin != nil {
+		errs = append(errs, s.admin.Close())
+	}
+	errs = append(errs, s.server.Close())
+	if err := s.processing.Wait(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("wait processing requests: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// shutdown drains srv, and closes the connections of it which are still
+// active when ctx is done.
+func shutdown(ctx context.Context, srv *http.Server) error {
+	err := srv.Shutdown(ctx)
 	if err != nil {
-		c.String(http.StatusBadRequest, "websocket.NewServer: %s", err)
-		return
+		_ = srv.Close()
 	}
-	defer srv.Close()
-	srv.Run()
+	return err
 }
 
-func (s *Server) processJsonRpcWithWS(c *g.Context) {
-	gobase.True(s.rpcServer != nil)
-	srv, err := websocket.NewServer(c, websocket.WithRpcServer(s.rpcServer))
+// abortWsSessions closes the websocket sessions at once and refuses the new ones.
+func (s *Server) abortWsSessions() {
+	s.wsMux.Lock()
+	defer s.wsMux.Unlock()
+
+	s.wsClosing = true
+	for srv := range s.wsSessions {
+		srv.Abort()
+	}
+}
+
+func (s *Server) addWsSession(srv *websocket.Server) bool {
+	s.wsMux.Lock()
+	defer s.wsMux.Unlock()
+
+	if s.wsClosing {
+		return false
+	}
+	s.wsSessions[srv] = struct{}{}
+	s.wsWg.Add(1)
+	return true
+}
+
+func (s *Server) removeWsSession(srv *websocket.Server) {
+	s.wsMux.Lock()
+	defer s.wsMux.Unlock()
+
+	delete(s.wsSessions, srv)
+	s.wsWg.Done()
+}
+
+// serveWs authenticates the handshake and serves the websocket session. A raw
+// websocket needs credentials at the handshake unless its path is public,
+// while json-rpc checks them for each method.
+func (s *Server) serveWs(c *g.Context, raw bool, opt websocket.WsOption) {
+	p, err := interceptor.Authenticate(s.opt.Auth, c.Request.Header)
+	if err == nil && p == nil && raw && s.opt.Auth != nil && !interceptor.IsPublic(s.opt.Auth, c.Request.URL.Path) {
+		err = api.NewJsonRpcErrFromCode(api.Unauthenticated, "credentials are required")
+	}
+	if err != nil {
+		err = interceptor.Denied(c.Request.URL.Path, err)
+		c.String(http.StatusUnauthorized, "%s", interceptor.JsonRpcError(err).Message)
+		return
+	}
+
+	opts := []websocket.WsOption{opt, websocket.WithInterceptor(s.interceptor), websocket.WithPrincipal(p),
+		websocket.WithReadLimit(s.opt.MaxWsMessageSize), websocket.WithProcessing(&s.processing)}
+	if s.cors != nil {
+		opts = append(opts, websocket.WithCheckOrigin(s.cors.CheckOrigin))
+	}
+	srv, err := websocket.NewServer(c, opts...)
 	if err != nil {
 		c.String(http.StatusBadRequest, "websocket.NewServer: %s", err)
 		return
 	}
+
+	if s.addWsSession(srv) {
+		defer s.removeWsSession(srv)
+	} else {
+		srv.GoingAway()
+	}
 	defer srv.Close()
 	srv.Run()
 }
 
-func (s *Server) processJsonRpcWithHttp(c *g.Context) {
-	body, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		resp := api.NewErrorJsonRpcResponse(nil, api.InternalError, api.SysCodeMap[api.InternalError], err.Error())
-		c.JSON(http.StatusOK, resp)
+func (s *Server) processGrpcWeb(c *g.Context) {
+	if s.grpcWeb == nil || !grpcweb.Match(c.Request) {
 		return
 	}
+	s.grpcWeb.ServeHTTP(c.Writer, c.Request)
+	c.Abort()
+}
+
+func (s *Server) processCors(c *g.Context) {
+	if s.cors.Handle(c.Writer, c.Request) {
+		c.Abort()
+	}
+}
+
+// authenticate verifies the credentials of c and keeps the principal in ctx.
+func (s *Server) authenticate(c *g.Context, adapter *ctx.ContextAdapter) error {
+	p, err := interceptor.Authenticate(s.opt.Auth, c.Request.Header)
+	if p != nil {
+		adapter.WithValue(api.PrincipalKey, p)
+	}
+	return err
+}
+
+// processLive reports that the process is able to serve http, even while it shuts down.
+func (s *Server) processLive(c *g.Context) {
+	c.JSON(http.StatusOK, &api.HealthResult{Status: api.HealthServing})
+}
+
+func (s *Server) processReady(c *g.Context) {
+	res := s.health.Ready(c.Request.Context())
+	code := http.StatusOK
+	if res.Status != api.HealthServing {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, res)
+}
 
-	ctx := newHttpContext("jsonRpc2", uuid.New().String(), len(body), c)
+func (s *Server) processRawWS(c *g.Context) {
+	gobase.True(s.rawWsHandle != nil)
+	s.serveWs(c, true, websocket.WithRawHandle(s.rawWsHandle))
+}
+
+func (s *Server) processJsonRpcWithWS(c *g.Context) {
+	gobase.True(s.rpcServer != nil)
+	s.serveWs(c, false, websocket.WithRpcServer(s.rpcServer))
+}
+
+func (s *Server) processJsonRpcWithHttp(c *g.Context) {
+	ctx := newHttpContext(&s.processing, "jsonRpc2", uuid.New().String(), int(max(c.Request.ContentLength, 0)), c)
+	codes := []int{api.Success}
 	defer func() {
-		ctx.EndRequest(api.Success)
+		ctx.EndRequests(codes)
 	}()
 
-	resp := s.rpcServer.Call(ctx, body)
-	c.JSON(http.StatusOK, resp)
+	if err := s.authenticate(c, &ctx.ContextAdapter); err != nil {
+		rpcErr := interceptor.JsonRpcError(interceptor.Denied(ctx.Name, err))
+		codes = []int{rpcErr.Code}
+		c.JSON(http.StatusUnauthorized, api.NewErrorJsonRpcResponseWithError(nil, rpcErr))
+		return
+	}
+
+	body, err := readBody(c, s.jsonRpcMaxBodySize())
+	if err != nil {
+		// like RegisterPath, the body which can't be read is an invalid request
+		codes = []int{api.InvalidRequest}
+		status := http.StatusBadRequest
+		if tooLarge(err) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.JSON(status, api.NewErrorJsonRpcResponse(nil, api.InvalidRequest, api.SysCodeMap[api.InvalidRequest], err.Error()))
+		return
+	}
+
+	resp := s.rpcServer.Call(ctx, api.TransportHttp, body)
+	codes = jsonrpc.Codes(resp)
+	status := http.StatusOK
+	// a single request refused by the rate or concurrency limits, or timed out, gets the
+	// http status of it, so that clients and proxies back off, while a batch is answered as usual
+	if r, ok := resp.(*api.JsonRpcResponse); ok && r.Error != nil {
+		switch r.Error.Code {
+		case api.ResourceExhausted, api.Unavailable, api.DeadlineExceeded:
+			if wait, ok := interceptor.RetryAfter(r.Error); ok {
+				setRetryAfter(c, wait)
+			}
+			status = interceptor.HttpStatus(r.Error)
+		}
+	}
+	c.JSON(status, resp)
+}
+
+func (s *Server) jsonRpcMaxBodySize() int64 {
+	if s.opt.JsonRpcOpt != nil && s.opt.JsonRpcOpt.MaxBodySize != 0 {
+		return s.opt.JsonRpcOpt.MaxBodySize
+	}
+	return s.opt.MaxBodySize
+}
+
+func setRetryAfter(c *g.Context, wait time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(wait/time.Second)))
 }
@@ -0,0 +1,156 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/health"
+	"github.com/BabySid/gorpc/internal/log"
+	ws "github.com/gorilla/websocket"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLog(nil)
+	os.Exit(m.Run())
+}
+
+// serve runs s on a local port and returns its address.
+func serve(t *testing.T, s *Server) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = s.Run(ln)
+	}()
+	return ln.Addr().String()
+}
+
+func TestShutdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		handle  time.Duration
+		timeout time.Duration
+		wantErr bool
+	}{
+		{name: "drained", handle: 50 * time.Millisecond, timeout: time.Second},
+		{name: "closed when ctx is done", handle: 10 * time.Second, timeout: 100 * time.Millisecond, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(api.ServerOption{}, health.New(), nil)
+			started := make(chan struct{})
+			err := s.RegisterPath(http.MethodGet, "/slow", func(c api.RawHttpContext, _ []byte) {
+				close(started)
+				select {
+				case <-time.After(tt.handle):
+				case <-c.Context().Done():
+				}
+				_ = c.WriteData(http.StatusOK, "text/plain", []byte("ok"))
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			addr := serve(t, s)
+
+			respErr := make(chan error, 1)
+			go func() {
+				resp, err := http.Get("http://" + addr + "/slow")
+				if err == nil {
+					_ = resp.Body.Close()
+				}
+				respErr <- err
+			}()
+			<-started
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			begin := time.Now()
+			err = s.Shutdown(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Shutdown() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Shutdown() = %v, want %v", err, context.DeadlineExceeded)
+			}
+			if cost := time.Since(begin); cost > tt.timeout+time.Second {
+				t.Errorf("Shutdown() took %v, want at most %v", cost, tt.timeout)
+			}
+
+			if err := <-respErr; (err != nil) != tt.wantErr {
+				t.Errorf("Get() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestShutdownWebsocket(t *testing.T) {
+	tests := []struct {
+		name string
+		// read lets the client answer the going away frame
+		read    bool
+		wantErr bool
+	}{
+		{name: "closed by the client", read: true},
+		{name: "aborted when ctx is done", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(api.ServerOption{}, health.New(), nil)
+			if err := s.RegisterRawWs(func(api.Context, api.WSMessage) error { return nil }); err != nil {
+				t.Fatal(err)
+			}
+			addr := serve(t, s)
+
+			conn, _, err := ws.DefaultDialer.Dial("ws://"+addr+"/"+api.BuiltInPathRawWS, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			if tt.read {
+				go func() {
+					for {
+						if _, _, err := conn.ReadMessage(); err != nil {
+							return
+						}
+					}
+				}()
+			}
+			// wait for the session to be served
+			for s.wsSessionCount() == 0 {
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			err = s.Shutdown(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Shutdown() = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// the session is gone well before the grace period of the going away frame
+			deadline := time.Now().Add(time.Second)
+			for s.wsSessionCount() > 0 {
+				if time.Now().After(deadline) {
+					t.Fatal("the websocket session is still open")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func (s *Server) wsSessionCount() int {
+	s.wsMux.Lock()
+	defer s.wsMux.Unlock()
+	return len(s.wsSessions)
+}
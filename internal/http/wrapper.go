@@ -1,14 +1,19 @@
 package http
 
 import (
+	"errors"
+	"io"
+	"net/http"
+
 	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/interceptor"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"io"
-	"net/http"
 )
 
-func getHandleWrapper(handle api.RawHttpHandle) func(ctx *gin.Context) {
+// handleWrapper serves handle on a raw route. The body is read by cfg once the
+// request is authenticated, and it is nil for GET, whose cfg is nil.
+func (s *Server) handleWrapper(handle api.RawHttpHandle, cfg *api.RawPath) func(ctx *gin.Context) {
 	return func(ctx *gin.Context) {
 		path := ctx.Request.URL.Path
 
@@ -16,34 +21,86 @@ func getHandleWrapper(handle api.RawHttpHandle) func(ctx *gin.Context) {
 		if v, ok := ctx.GetQuery("id"); ok {
 			id = v
 		}
-		myCtx := newRawContext(path, id, 0, ctx)
-		defer func() {
-			myCtx.EndRequest(api.Success)
-		}()
+		myCtx := newRawContext(&s.processing, path, id, 0, ctx)
 
-		handle(myCtx, nil)
+		err := s.invokeRaw(myCtx, path, cfg, handle)
+		myCtx.EndRequest(interceptor.Code(api.TransportRawHttp, err))
 	}
 }
 
-func postHandleWrapper(handle api.RawHttpHandle) func(ctx *gin.Context) {
-	return func(ctx *gin.Context) {
-		path := ctx.Request.URL.Path
-
-		id := uuid.New().String()
-		if v, ok := ctx.GetQuery("id"); ok {
-			id = v
+// invokeRaw authenticates the request, reads the body and calls handle through
+// the interceptors. Like a raw websocket, the credentials are required before
+// the body is read unless path is public. The error or panic is written as the
+// response unless one has been written.
+func (s *Server) invokeRaw(ctx *RawContext, path string, cfg *api.RawPath, handle api.RawHttpHandle) (err error) {
+	defer func() {
+		if err != nil && !ctx.ctx.Writer.Written() {
+			if wait, ok := interceptor.RetryAfter(err); ok {
+				setRetryAfter(ctx.ctx, wait)
+			}
+			ctx.ctx.String(interceptor.HttpStatus(err), "%s", interceptor.JsonRpcError(err).Message)
 		}
-		myCtx := newRawContext(path, id, 0, ctx)
-		defer func() {
-			myCtx.EndRequest(api.Success)
-		}()
-
-		body, err := io.ReadAll(ctx.Request.Body)
-		if err != nil {
-			ctx.String(http.StatusBadRequest, "read body err: %v", err)
-			return
+	}()
+	defer interceptor.Recover(ctx, path, &err)
+
+	if err = s.authenticate(ctx.ctx, &ctx.ContextAdapter); err != nil {
+		return interceptor.Denied(path, err)
+	}
+	if s.opt.Auth != nil && api.PrincipalOf(ctx) == nil && !interceptor.IsPublic(s.opt.Auth, path) {
+		return interceptor.Denied(path, api.NewJsonRpcErrFromCode(api.Unauthenticated, "credentials are required"))
+	}
+
+	var body []byte
+	if cfg != nil {
+		if body, err = rawBody(ctx.ctx, *cfg); err != nil {
+			code := http.StatusBadRequest
+			if tooLarge(err) {
+				code = http.StatusRequestEntityTooLarge
+			}
+			ctx.ctx.String(code, "read body err: %v", err)
+			return api.NewJsonRpcErrFromCode(api.InvalidRequest, err.Error())
 		}
+	}
+
+	info := &api.CallInfo{Transport: api.TransportRawHttp, Method: path, Request: body}
+	_, err = interceptor.Invoke(s.interceptor, ctx, info, func(c api.Context) (interface{}, error) {
+		handle(rawContextOf(c, ctx), body)
+		return nil, nil
+	})
+	return err
+}
+
+// rawBody reads the body of a raw route by cfg, or only bounds it for the
+// handle to stream it.
+func rawBody(ctx *gin.Context, cfg api.RawPath) ([]byte, error) {
+	if cfg.StreamBody {
+		limitBody(ctx, cfg.MaxBodySize)
+		return nil, nil
+	}
+	return readBody(ctx, cfg.MaxBodySize)
+}
 
-		handle(myCtx, body)
+// rawContextOf keeps the context passed by the interceptors if it is still a RawHttpContext.
+func rawContextOf(c api.Context, raw *RawContext) api.RawHttpContext {
+	if rc, ok := c.(api.RawHttpContext); ok {
+		return rc
 	}
+	return raw
+}
+
+// limitBody bounds the body of ctx by max if it is positive.
+func limitBody(ctx *gin.Context, max int64) {
+	if max > 0 {
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, max)
+	}
+}
+
+func readBody(ctx *gin.Context, max int64) ([]byte, error) {
+	limitBody(ctx, max)
+	return io.ReadAll(ctx.Request.Body)
+}
+
+func tooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
 }
@@ -0,0 +1,172 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/health"
+	"github.com/gin-gonic/gin"
+)
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestReadBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        io.Reader
+		max         int64
+		want        string
+		wantErr     bool
+		wantTooLong bool
+	}{
+		{name: "unlimited", body: strings.NewReader("hello"), want: "hello"},
+		{name: "under the limit", body: strings.NewReader("hello"), max: 8, want: "hello"},
+		{name: "at the limit", body: strings.NewReader("hello"), max: 5, want: "hello"},
+		{name: "over the limit", body: strings.NewReader("hello"), max: 4, wantErr: true, wantTooLong: true},
+		{name: "read error", body: errReader{}, max: 4, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/", tt.body)
+
+			got, err := readBody(c, tt.max)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readBody() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tooLarge(err) != tt.wantTooLong {
+				t.Errorf("tooLarge(%v) = %v, want %v", err, !tt.wantTooLong, tt.wantTooLong)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("readBody() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBodySize(t *testing.T) {
+	s := NewServer(api.ServerOption{
+		MaxBodySize: 8,
+		JsonRpcOpt:  &api.JsonRpcOption{MaxBodySize: 16},
+	}, health.New(), nil)
+	handle := func(c api.RawHttpContext, body []byte) {
+		if body == nil {
+			var err error
+			if body, err = io.ReadAll(c.(api.RawHttpBodyContext).Body()); tooLarge(err) {
+				_ = c.WriteData(http.StatusRequestEntityTooLarge, "text/plain", nil)
+				return
+			}
+		}
+		_ = c.WriteData(http.StatusOK, "text/plain", body)
+	}
+	if err := s.RegisterPath(http.MethodPost, "/echo", handle); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterPath(http.MethodPost, "/upload", handle, api.WithStreamBody(), api.WithMaxBodySize(32)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterPath(http.MethodGet, "/get", handle, api.WithMaxBodySize(32)); err != invalidBodyOption {
+		t.Errorf("RegisterPath(GET) = %v, want %v", err, invalidBodyOption)
+	}
+	addr := serve(t, s)
+
+	tests := []struct {
+		name string
+		path string
+		size int
+		want int
+	}{
+		{name: "server limit", path: "/echo", size: 8, want: http.StatusOK},
+		{name: "over server limit", path: "/echo", size: 9, want: http.StatusRequestEntityTooLarge},
+		{name: "route limit", path: "/upload", size: 32, want: http.StatusOK},
+		{name: "over route limit", path: "/upload", size: 33, want: http.StatusRequestEntityTooLarge},
+		{name: "json-rpc limit", path: "/" + api.BuiltInPathJsonRPC, size: 16, want: http.StatusOK},
+		{name: "over json-rpc limit", path: "/" + api.BuiltInPathJsonRPC, size: 17, want: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post("http://"+addr+tt.path, "text/plain", bytes.NewReader(make([]byte, tt.size)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("Post(%s) = %d, want %d", tt.path, resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+type tokenAuth struct{}
+
+func (tokenAuth) Authenticate(header http.Header) (*api.Principal, error) {
+	switch header.Get("Authorization") {
+	case "":
+		return nil, nil
+	case "Bearer good":
+		return &api.Principal{Subject: "alice", Scheme: "test"}, nil
+	}
+	return nil, errors.New("invalid token")
+}
+
+func TestAuthBeforeBody(t *testing.T) {
+	s := NewServer(api.ServerOption{
+		MaxBodySize: 8,
+		Auth:        &api.AuthOption{Authenticators: []api.Authenticator{tokenAuth{}}, Public: []string{"/public"}},
+	}, health.New(), nil)
+	handle := func(c api.RawHttpContext, body []byte) {
+		_ = c.WriteData(http.StatusOK, "text/plain", body)
+	}
+	for _, path := range []string{"/private", "/public"} {
+		if err := s.RegisterPath(http.MethodPost, path, handle); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addr := serve(t, s)
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		size  int
+		want  int
+	}{
+		{name: "bad token", path: "/private", token: "bad", size: 9, want: http.StatusUnauthorized},
+		{name: "no token", path: "/private", size: 9, want: http.StatusUnauthorized},
+		{name: "good token", path: "/private", token: "good", size: 9, want: http.StatusRequestEntityTooLarge},
+		{name: "good token in the limit", path: "/private", token: "good", size: 8, want: http.StatusOK},
+		{name: "public", path: "/public", size: 9, want: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "http://"+addr+tt.path, bytes.NewReader(make([]byte, tt.size)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("Post(%s) = %d, want %d", tt.path, resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package interceptor
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/log"
+	"github.com/BabySid/gorpc/metrics"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// FromOption chains the built-in interceptors and the ones of option.
+func FromOption(option api.ServerOption) api.Interceptor {
+	var interceptors []api.Interceptor
+	if option.RateLimitOpt != nil {
+		interceptors = append(interceptors, rateLimit(option.RateLimitOpt))
+	}
+	if option.Auth != nil {
+		interceptors = append(interceptors, auth(option.Auth))
+	}
+	if option.Concurrency != nil {
+		interceptors = append(interceptors, bulkhead(option.Concurrency))
+	}
+	return Chain(append(interceptors, option.Interceptors...)...)
+}
+
+// Authenticate verifies the credentials in header by the authenticators of opt.
+// It returns nil and no error if there are no credentials.
+func Authenticate(opt *api.AuthOption, header http.Header) (*api.Principal, error) {
+	if opt == nil {
+		return nil, nil
+	}
+	for _, a := range opt.Authenticators {
+		p, err := a.Authenticate(header)
+		if err != nil {
+			return nil, api.NewJsonRpcErrFromCode(api.Unauthenticated, err.Error())
+		}
+		if p != nil {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+// Denied counts err, which refuses the call of method as Unauthenticated
+// or PermissionDenied, by request_denied_total and returns it.
+func Denied(method string, err error) error {
+	reason := "unauthenticated"
+	if JsonRpcError(err).Code == api.PermissionDenied {
+		reason = "permission_denied"
+	}
+	metrics.DeniedRequests.WithLabelValues(metrics.GetCluster(), method, reason).Inc()
+	return err
+}
+
+// IsPublic reports whether method may be called without credentials.
+func IsPublic(opt *api.AuthOption, method string) bool {
+	if isHealth(method) {
+		return true
+	}
+	for _, pattern := range opt.Public {
+		if matched, _ := path.Match(pattern, method); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func isHealth(method string) bool {
+	return strings.HasPrefix(method, "/"+grpc_health_v1.Health_ServiceDesc.ServiceName+"/")
+}
+
+// auth refuses the calls without a principal unless their methods are public,
+// and the calls which are not authorized by the policies.
+func auth(opt *api.AuthOption) api.Interceptor {
+	return func(ctx api.Context, info *api.CallInfo, invoke api.Invoker) (interface{}, error) {
+		if IsPublic(opt, info.Method) {
+			return invoke(ctx)
+		}
+
+		p := api.PrincipalOf(ctx)
+		if p == nil {
+			return nil, Denied(info.Method, api.NewJsonRpcErrFromCode(api.Unauthenticated, "credentials are required"))
+		}
+		if err := authorize(opt, p, info.Method); err != nil {
+			log.DefaultLog.Warn("permission denied", slog.String("method", info.Method),
+				slog.String("subject", p.Subject), slog.String("reason", err.Error()))
+			return nil, Denied(info.Method, api.NewJsonRpcErrFromCode(api.PermissionDenied, err.Error()))
+		}
+		return invoke(ctx)
+	}
+}
+
+// authorize checks p against every policy of opt which matches method.
+func authorize(opt *api.AuthOption, p *api.Principal, method string) error {
+	for _, policy := range opt.Policies {
+		matched, err := path.Match(policy.Pattern, method)
+		if err != nil {
+			return fmt.Errorf("bad pattern of policy %q", policy.Pattern)
+		}
+		if !matched {
+			continue
+		}
+		if len(policy.Roles) > 0 && !containsAny(p.Roles, policy.Roles) {
+			return fmt.Errorf("one of roles %v is required", policy.Roles)
+		}
+		for _, scope := range policy.Scopes {
+			if !containsAny(p.Scopes, []string{scope}) {
+				return fmt.Errorf("scope %s is required", scope)
+			}
+		}
+	}
+	return nil
+}
+
+func containsAny(have []string, want []string) bool {
+	for _, h := range have {
+		for _, w := range want {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package interceptor
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/BabySid/gorpc/api"
+)
+
+type authenticatorFunc func(http.Header) (*api.Principal, error)
+
+func (f authenticatorFunc) Authenticate(header http.Header) (*api.Principal, error) {
+	return f(header)
+}
+
+func TestAuthenticate(t *testing.T) {
+	alice := &api.Principal{Subject: "alice", Scheme: "test"}
+	none := authenticatorFunc(func(http.Header) (*api.Principal, error) {
+		return nil, nil
+	})
+	found := authenticatorFunc(func(http.Header) (*api.Principal, error) {
+		return alice, nil
+	})
+	invalid := authenticatorFunc(func(http.Header) (*api.Principal, error) {
+		return nil, errors.New("invalid credentials")
+	})
+
+	tests := []struct {
+		name     string
+		opt      *api.AuthOption
+		want     *api.Principal
+		wantCode int
+	}{
+		{name: "no option"},
+		{name: "no credentials", opt: &api.AuthOption{Authenticators: []api.Authenticator{none}}},
+		{name: "second authenticator", opt: &api.AuthOption{Authenticators: []api.Authenticator{none, found}}, want: alice},
+		{name: "first one wins", opt: &api.AuthOption{Authenticators: []api.Authenticator{found, invalid}}, want: alice},
+		{name: "invalid", opt: &api.AuthOption{Authenticators: []api.Authenticator{invalid, found}}, wantCode: api.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Authenticate(tt.opt, http.Header{})
+			if got != tt.want {
+				t.Errorf("Authenticate() = %+v, want %+v", got, tt.want)
+			}
+			if tt.wantCode == 0 {
+				if err != nil {
+					t.Errorf("Authenticate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || JsonRpcError(err).Code != tt.wantCode {
+				t.Errorf("Authenticate() error = %v, want code %d", err, tt.wantCode)
+			}
+		})
+	}
+}
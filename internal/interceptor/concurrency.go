@@ -0,0 +1,46 @@
+package interceptor
+
+import (
+	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/concurrency"
+	"github.com/BabySid/gorpc/metrics"
+)
+
+// BulkheadServer is the bulkhead of the whole server in the metrics.
+const BulkheadServer = "server"
+
+// BulkheadService is the bulkhead of a json-rpc service in the metrics,
+// which never collides with BulkheadServer.
+func BulkheadService(service string) string {
+	return "service:" + service
+}
+
+// bulkhead sheds the calls over limit of the server. The health checks are
+// never shed, and the grpc streams, which may last as long as their
+// connections, are not limited.
+func bulkhead(limit *api.ConcurrencyLimit) api.Interceptor {
+	l := concurrency.NewLimiter(BulkheadServer, *limit)
+	return func(ctx api.Context, info *api.CallInfo, invoke api.Invoker) (interface{}, error) {
+		if isHealth(info.Method) || isStream(info) {
+			return invoke(ctx)
+		}
+
+		release, err := l.Acquire(ctx.Context())
+		if err != nil {
+			return nil, Shed(info.Method, BulkheadServer, err)
+		}
+		defer release()
+		return invoke(ctx)
+	}
+}
+
+// Shed counts a call of method shed by the bulkhead and returns its error.
+func Shed(method string, bulkhead string, err error) *api.JsonRpcError {
+	metrics.ShedRequests.WithLabelValues(metrics.GetCluster(), method, bulkhead).Inc()
+	return api.NewJsonRpcErrFromCode(api.Unavailable, err.Error())
+}
+
+// isStream reports whether info is a grpc stream, which has no request message.
+func isStream(info *api.CallInfo) bool {
+	return info.Transport == api.TransportGrpc && info.Request == nil
+}
@@ -0,0 +1,119 @@
+package interceptor
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/BabySid/gorpc/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// JsonRpcError converts err of a call to a json-rpc error.
+func JsonRpcError(err error) *api.JsonRpcError {
+	var rpcErr *api.JsonRpcError
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	if st, ok := status.FromError(err); ok {
+		return api.NewJsonRpcError(jsonRpcCode(st.Code()), st.Message(), nil)
+	}
+	return api.NewJsonRpcErrFromCode(api.InternalError, err.Error())
+}
+
+// GrpcError converts err of a call to a grpc status error.
+func GrpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	rpcErr := JsonRpcError(err)
+	return status.Error(grpcCode(rpcErr.Code), rpcErr.Message)
+}
+
+// HttpStatus is the http status of err of a call.
+func HttpStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	switch JsonRpcError(err).Code {
+	case api.ParseError, api.InvalidRequest, api.InvalidParams:
+		return http.StatusBadRequest
+	case api.MethodNotFound:
+		return http.StatusNotFound
+	case api.Unauthenticated:
+		return http.StatusUnauthorized
+	case api.PermissionDenied:
+		return http.StatusForbidden
+	case api.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case api.Unavailable:
+		return http.StatusServiceUnavailable
+	case api.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Code is the status code of err for metrics, a grpc code for grpc calls
+// and a json-rpc code for the others.
+func Code(transport api.Transport, err error) int {
+	if transport == api.TransportGrpc {
+		return int(status.Code(err))
+	}
+	if err == nil {
+		return api.Success
+	}
+	return JsonRpcError(err).Code
+}
+
+func grpcCode(code int) codes.Code {
+	switch code {
+	case api.Success:
+		return codes.OK
+	case api.ParseError, api.InvalidRequest, api.InvalidParams:
+		return codes.InvalidArgument
+	case api.MethodNotFound:
+		return codes.Unimplemented
+	case api.InternalError:
+		return codes.Internal
+	case api.Unauthenticated:
+		return codes.Unauthenticated
+	case api.PermissionDenied:
+		return codes.PermissionDenied
+	case api.ResourceExhausted:
+		return codes.ResourceExhausted
+	case api.Unavailable:
+		return codes.Unavailable
+	case api.DeadlineExceeded:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Unknown
+	}
+}
+
+func jsonRpcCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return api.Success
+	case codes.InvalidArgument:
+		return api.InvalidParams
+	case codes.Unimplemented:
+		return api.MethodNotFound
+	case codes.Unauthenticated:
+		return api.Unauthenticated
+	case codes.PermissionDenied:
+		return api.PermissionDenied
+	case codes.ResourceExhausted:
+		return api.ResourceExhausted
+	case codes.Unavailable:
+		return api.Unavailable
+	case codes.DeadlineExceeded:
+		return api.DeadlineExceeded
+	default:
+		return api.InternalError
+	}
+}
@@ -0,0 +1,37 @@
+package interceptor
+
+import (
+	"github.com/BabySid/gorpc/api"
+)
+
+// Chain merges the interceptors into one, the first one is the outermost.
+// It returns nil if there is no interceptor.
+func Chain(interceptors ...api.Interceptor) api.Interceptor {
+	switch len(interceptors) {
+	case 0:
+		return nil
+	case 1:
+		return interceptors[0]
+	}
+
+	return func(ctx api.Context, info *api.CallInfo, invoke api.Invoker) (interface{}, error) {
+		return interceptors[0](ctx, info, chained(interceptors[1:], info, invoke))
+	}
+}
+
+func chained(interceptors []api.Interceptor, info *api.CallInfo, invoke api.Invoker) api.Invoker {
+	if len(interceptors) == 0 {
+		return invoke
+	}
+	return func(ctx api.Context) (interface{}, error) {
+		return interceptors[0](ctx, info, chained(interceptors[1:], info, invoke))
+	}
+}
+
+// Invoke calls handler through i, which may be nil.
+func Invoke(i api.Interceptor, ctx api.Context, info *api.CallInfo, handler api.Invoker) (interface{}, error) {
+	if i == nil {
+		return handler(ctx)
+	}
+	return i(ctx, info, handler)
+}
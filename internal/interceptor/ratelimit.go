@@ -0,0 +1,50 @@
+package interceptor
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/ratelimit"
+	"github.com/BabySid/gorpc/metrics"
+)
+
+const retryAfterKey = "retry_after"
+
+// rateLimit rejects the calls over the limits of opt. The health checks are never limited.
+func rateLimit(opt *api.RateLimitOption) api.Interceptor {
+	l := ratelimit.NewLimiter(opt)
+	return func(ctx api.Context, info *api.CallInfo, invoke api.Invoker) (interface{}, error) {
+		if isHealth(info.Method) {
+			return invoke(ctx)
+		}
+
+		ok, scope, wait := l.Allow(callerOf(ctx), info.Method)
+		if !ok {
+			metrics.RateLimitedRequests.WithLabelValues(metrics.GetCluster(), info.Method, scope).Inc()
+			seconds := int(math.Ceil(wait.Seconds()))
+			return nil, api.NewJsonRpcErrFromCode(api.ResourceExhausted, map[string]interface{}{retryAfterKey: seconds})
+		}
+		return invoke(ctx)
+	}
+}
+
+// callerOf identifies the caller by its principal, or by its ip without one.
+func callerOf(ctx api.Context) string {
+	if p := api.PrincipalOf(ctx); p != nil {
+		return p.Scheme + ":" + p.Subject
+	}
+	return "ip:" + ctx.ClientIP()
+}
+
+// RetryAfter returns how long the caller should wait if err is a ResourceExhausted error.
+func RetryAfter(err error) (time.Duration, bool) {
+	var rpcErr *api.JsonRpcError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != api.ResourceExhausted {
+		return 0, false
+	}
+	data, _ := rpcErr.Data.(map[string]interface{})
+	seconds, ok := data[retryAfterKey].(int)
+	return time.Duration(seconds) * time.Second, ok
+}
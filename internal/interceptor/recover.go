@@ -0,0 +1,48 @@
+package interceptor
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/log"
+	"github.com/BabySid/gorpc/metrics"
+)
+
+// Panic is a panic raised again on the goroutine of the call, which keeps
+// the stack of the goroutine where it was raised.
+type Panic struct {
+	Value interface{}
+	Stack []byte
+}
+
+// Recover turns a panic of the call of method into an InternalError in *err,
+// which refers to the id of ctx rather than exposing the panic to the client,
+// and logs the panic with the stack and attrs, e.g. the id of a json-rpc request
+// in a batch. It must be deferred directly.
+func Recover(ctx api.Context, method string, err *error, attrs ...slog.Attr) {
+	p := recover()
+	if p == nil {
+		return
+	}
+
+	pp, ok := p.(*Panic)
+	if !ok {
+		pp = &Panic{Value: p, Stack: debug.Stack()}
+	}
+	LogPanic(ctx, method, pp, attrs...)
+	*err = api.NewJsonRpcErrFromCode(api.InternalError, fmt.Sprintf("panic in request %v", ctx.CtxID()))
+}
+
+// LogPanic logs and counts a panic of the call of method, e.g. the one which
+// can't fail the call since it is raised after the call timed out.
+func LogPanic(ctx api.Context, method string, p *Panic, attrs ...slog.Attr) {
+	args := []any{slog.Any("ctxID", ctx.CtxID()), slog.String("method", method)}
+	for _, attr := range attrs {
+		args = append(args, attr)
+	}
+	args = append(args, slog.Any("panic", p.Value), slog.String("stack", string(p.Stack)))
+	log.DefaultLog.Error("panic recovered", args...)
+	metrics.PanicRequests.WithLabelValues(metrics.GetCluster(), method).Inc()
+}
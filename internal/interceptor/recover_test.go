@@ -0,0 +1,68 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/log"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLog(nil)
+	os.Exit(m.Run())
+}
+
+type testContext struct{}
+
+func (testContext) CtxID() interface{}              { return "ctx-1" }
+func (testContext) ClientIP() string                { return "127.0.0.1" }
+func (testContext) PeerIdentity() *api.PeerIdentity { return nil }
+func (testContext) WithValue(string, any)           {}
+func (testContext) Value(string) (any, bool)        { return nil, false }
+func (testContext) Context() context.Context        { return context.Background() }
+
+func TestRecover(t *testing.T) {
+	fail := errors.New("failed")
+
+	tests := []struct {
+		name     string
+		call     func() error
+		wantErr  error
+		wantCode int
+	}{
+		{name: "no panic", call: func() error { return nil }},
+		{name: "error", call: func() error { return fail }, wantErr: fail},
+		{name: "panic", call: func() error { panic("boom") }, wantCode: api.InternalError},
+		{name: "panic with error", call: func() error { panic(fail) }, wantCode: api.InternalError},
+		{name: "panic raised again", call: func() error { panic(&Panic{Value: "boom", Stack: []byte("stack")}) }, wantCode: api.InternalError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := func() (err error) {
+				defer Recover(testContext{}, "rpc.Add", &err, slog.Any("reqID", 1))
+				return tt.call()
+			}()
+
+			if tt.wantCode == 0 {
+				if err != tt.wantErr {
+					t.Errorf("Recover() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			e := JsonRpcError(err)
+			if e.Code != tt.wantCode {
+				t.Errorf("Recover() error = %v, want code %d", err, tt.wantCode)
+			}
+			// the panic is not exposed to the client
+			if data, _ := e.Data.(string); strings.Contains(data, "boom") || !strings.Contains(data, "ctx-1") {
+				t.Errorf("Recover() data = %q, want the ctx id only", data)
+			}
+		})
+	}
+}
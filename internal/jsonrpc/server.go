@@ -4,12 +4,15 @@ import (
 	"errors"
 	"go/token"
 	"log/slog"
+	"path"
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/BabySid/gorpc/api"
 	"github.com/BabySid/gorpc/codec"
+	"github.com/BabySid/gorpc/internal/interceptor"
 	"github.com/BabySid/gorpc/internal/log"
 )
 
@@ -28,6 +31,10 @@ type Server struct {
 
 type Option struct {
 	CodeType codec.CodecType
+	// Interceptor wraps the call of every method, it may be nil.
+	Interceptor api.Interceptor
+	// Timeout is the default timeout of the methods.
+	Timeout time.Duration
 }
 
 // NewServer returns a new Server.
@@ -35,17 +42,17 @@ func NewServer(opt Option) *Server {
 	return &Server{opt: opt}
 }
 
-func (server *Server) Register(receiver interface{}) error {
-	return server.register(receiver, "", false)
+func (server *Server) Register(receiver interface{}, opts ...api.JsonRpcServiceOption) error {
+	return server.register(receiver, "", false, opts)
 }
 
 // RegisterName is like Register but uses the provided name for the type
 // instead of the receiver's concrete type.
-func (server *Server) RegisterName(name string, receiver interface{}) error {
-	return server.register(receiver, name, true)
+func (server *Server) RegisterName(name string, receiver interface{}, opts ...api.JsonRpcServiceOption) error {
+	return server.register(receiver, name, true, opts)
 }
 
-func (server *Server) register(receiver interface{}, name string, useName bool) error {
+func (server *Server) register(receiver interface{}, name string, useName bool, opts []api.JsonRpcServiceOption) error {
 	s := new(service)
 	s.typ = reflect.TypeOf(receiver)
 	s.receiver = reflect.ValueOf(receiver)
@@ -77,6 +84,44 @@ func (server *Server) register(receiver interface{}, name string, useName bool)
 		return errors.New(str)
 	}
 
+	var cfg api.JsonRpcService
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	var bulkhead api.JsonRpcMiddleware
+	if cfg.Concurrency != nil {
+		bulkhead = newBulkhead(serverName, *cfg.Concurrency)
+	}
+	for mName, mType := range s.method {
+		mType.desc = &api.MethodDesc{Service: serverName, Method: mName, ArgType: mType.ArgType, ReplyType: mType.ReplyType}
+
+		var middlewares []api.JsonRpcMiddleware
+		if bulkhead != nil {
+			middlewares = append(middlewares, bulkhead)
+		}
+		for _, rule := range cfg.Middlewares {
+			matched, err := path.Match(rule.Pattern, serverName+"."+mName)
+			if err != nil {
+				return errors.New("rpc.Register: invalid middleware pattern " + rule.Pattern)
+			}
+			if matched {
+				middlewares = append(middlewares, rule.Middleware)
+			}
+		}
+
+		timeout := server.opt.Timeout
+		for _, rule := range cfg.Timeouts {
+			matched, err := path.Match(rule.Pattern, serverName+"."+mName)
+			if err != nil {
+				return errors.New("rpc.Register: invalid timeout pattern " + rule.Pattern)
+			}
+			if matched {
+				timeout = rule.Timeout
+			}
+		}
+		s.buildHandler(mType, middlewares, timeout)
+	}
+
 	// todo register multi method
 	if _, dup := server.serviceMap.LoadOrStore(serverName, s); dup {
 		return errors.New("rpc: service already defined: " + serverName)
@@ -146,7 +191,8 @@ func suitableMethods(typ reflect.Type) map[string]*methodType {
 	return methods
 }
 
-func (server *Server) Call(ctx api.Context, data []byte) interface{} {
+// Call handles a json-rpc request or batch from transport.
+func (server *Server) Call(ctx api.Context, transport api.Transport, data []byte) interface{} {
 	msgs, batch, err := ParseBatchMessage(data)
 	if err != nil {
 		return api.NewErrorJsonRpcResponseWithError(nil,
@@ -161,12 +207,12 @@ func (server *Server) Call(ctx api.Context, data []byte) interface{} {
 		}
 		resArr := make([]interface{}, 0, len(msgs))
 		for _, msg := range msgs {
-			res := server.processRequest(ctx, msg)
+			res := server.processRequest(ctx, transport, msg)
 			resArr = append(resArr, res)
 		}
 		return resArr
 	} else {
-		res := server.processRequest(ctx, msgs[0])
+		res := server.processRequest(ctx, transport, msgs[0])
 		return res
 	}
 	//reqData, err := parseRequestBody(data)
@@ -202,7 +248,32 @@ func (server *Server) Call(ctx api.Context, data []byte) interface{} {
 	//}
 }
 
-func (server *Server) processRequest(ctx api.Context, req *Message) *api.JsonRpcResponse {
+// Codes are the status of resp of Call for the metrics, the error code of
+// each response, so that every request of a batch is counted.
+func Codes(resp interface{}) []int {
+	switch r := resp.(type) {
+	case *api.JsonRpcResponse:
+		return []int{code(r)}
+	case []interface{}:
+		codes := make([]int, 0, len(r))
+		for _, item := range r {
+			if res, ok := item.(*api.JsonRpcResponse); ok {
+				codes = append(codes, code(res))
+			}
+		}
+		return codes
+	}
+	return []int{api.Success}
+}
+
+func code(resp *api.JsonRpcResponse) int {
+	if resp.Error != nil {
+		return resp.Error.Code
+	}
+	return api.Success
+}
+
+func (server *Server) processRequest(ctx api.Context, transport api.Transport, req *Message) *api.JsonRpcResponse {
 	rpcErr := checkMessage(req)
 	if rpcErr != nil {
 		return api.NewErrorJsonRpcResponseWithError(req.ID, rpcErr)
@@ -266,11 +337,24 @@ func (server *Server) processRequest(ctx api.Context, req *Message) *api.JsonRpc
 	//	replyValue.Elem().Set(reflect.MakeSlice(mType.ReplyType.Elem(), 0, 0))
 	//}
 
-	replyValue, err := svc.call(mType, reflect.ValueOf(ctx), argv)
-	apiErr := err.(*api.JsonRpcError)
-	if apiErr != nil {
-		return api.NewErrorJsonRpcResponseWithError(req.ID, apiErr)
+	info := &api.CallInfo{Transport: transport, Method: req.Method, Request: argv.Interface()}
+	replyValue, err := server.invoke(ctx, req.ID, info, mType)
+	if err != nil {
+		return api.NewErrorJsonRpcResponseWithError(req.ID, interceptor.JsonRpcError(err))
 	}
 
 	return api.NewSuccessJsonRpcResponse(req.ID, replyValue)
 }
+
+// invoke calls the method through the interceptor. A panic fails this request only.
+func (server *Server) invoke(ctx api.Context, id interface{}, info *api.CallInfo, mType *methodType) (replyValue interface{}, err error) {
+	defer interceptor.Recover(ctx, info.Method, &err, slog.Any("reqID", id))
+
+	return interceptor.Invoke(server.opt.Interceptor, ctx, info, func(ctx api.Context) (interface{}, error) {
+		replyValue, apiErr := mType.handler(ctx, info.Request)
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		return replyValue, nil
+	})
+}
@@ -1,12 +1,27 @@
 package jsonrpc
 
-import "reflect"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"time"
+
+	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/concurrency"
+	"github.com/BabySid/gorpc/internal/interceptor"
+)
 
 type methodType struct {
 	method    reflect.Method
 	ArgType   reflect.Type
 	ReplyType reflect.Type
 	//numCalls   uint
+
+	desc *api.MethodDesc
+	// handler calls the method through its middlewares
+	handler api.JsonRpcHandler
 }
 
 type service struct {
@@ -27,3 +42,113 @@ func (s *service) call(mType *methodType, ctx reflect.Value, argv reflect.Value)
 	err := returnValues[1].Interface()
 	return reply, err
 }
+
+// buildHandler chains the middlewares in front of the method, and bounds them by timeout if it is positive.
+func (s *service) buildHandler(mType *methodType, middlewares []api.JsonRpcMiddleware, timeout time.Duration) {
+	next := func(ctx api.Context, args interface{}) (interface{}, *api.JsonRpcError) {
+		argv := reflect.ValueOf(args)
+		if !argv.IsValid() {
+			argv = reflect.Zero(mType.ArgType)
+		}
+		if !argv.Type().AssignableTo(mType.ArgType) {
+			return nil, api.NewJsonRpcErrFromCode(api.InvalidParams,
+				fmt.Sprintf("rpc: args of %s.%s must be %s, not %s", s.name, mType.method.Name, mType.ArgType, argv.Type()))
+		}
+		reply, err := s.call(mType, reflect.ValueOf(&ctx).Elem(), argv)
+		return reply, err.(*api.JsonRpcError)
+	}
+
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		mw, inner := middlewares[i], next
+		next = func(ctx api.Context, args interface{}) (interface{}, *api.JsonRpcError) {
+			return mw(ctx, mType.desc, args, inner)
+		}
+	}
+	if timeout > 0 {
+		next = withTimeout(next, mType.desc, timeout)
+	}
+	mType.handler = next
+}
+
+// apiContext is embedded by its alias, whose field does not clash with the method Context.
+type apiContext = api.Context
+
+// timeoutContext is the api.Context of a method with a timeout.
+type timeoutContext struct {
+	apiContext
+	ctx context.Context
+}
+
+func (c *timeoutContext) Context() context.Context {
+	return c.ctx
+}
+
+// withTimeout returns a DeadlineExceeded error once timeout expires. The handler
+// can not be stopped, so it keeps running on its own goroutine and sees the
+// cancellation by its context. Its panic is raised again on the goroutine of
+// the call, or only logged once the call has timed out.
+func withTimeout(handler api.JsonRpcHandler, desc *api.MethodDesc, timeout time.Duration) api.JsonRpcHandler {
+	type result struct {
+		reply interface{}
+		err   *api.JsonRpcError
+		panic *interceptor.Panic
+	}
+	method := desc.Service + "." + desc.Method
+
+	return func(ctx api.Context, args interface{}) (interface{}, *api.JsonRpcError) {
+		c, cancel := context.WithTimeout(ctx.Context(), timeout)
+		defer cancel()
+
+		// done is unbuffered, so the handler either hands over its result
+		// or finds the call abandoned
+		done := make(chan result)
+		abandoned := make(chan struct{})
+		go func() {
+			var r result
+			panicked := true
+			defer func() {
+				if panicked {
+					r.panic = &interceptor.Panic{Value: recover(), Stack: debug.Stack()}
+				}
+				select {
+				case done <- r:
+				case <-abandoned:
+					if r.panic != nil {
+						interceptor.LogPanic(ctx, method, r.panic)
+					}
+				}
+			}()
+			r.reply, r.err = handler(&timeoutContext{apiContext: ctx, ctx: c}, args)
+			panicked = false
+		}()
+
+		select {
+		case r := <-done:
+			if r.panic != nil {
+				panic(r.panic)
+			}
+			return r.reply, r.err
+		case <-c.Done():
+			close(abandoned)
+			if errors.Is(c.Err(), context.DeadlineExceeded) {
+				return nil, api.NewJsonRpcErrFromCode(api.DeadlineExceeded,
+					fmt.Sprintf("rpc: %s timed out after %s", method, timeout))
+			}
+			return nil, api.NewJsonRpcErrFromCode(api.InternalError, c.Err().Error())
+		}
+	}
+}
+
+// newBulkhead limits the calls in flight of the methods of service together.
+func newBulkhead(service string, limit api.ConcurrencyLimit) api.JsonRpcMiddleware {
+	name := interceptor.BulkheadService(service)
+	l := concurrency.NewLimiter(name, limit)
+	return func(ctx api.Context, desc *api.MethodDesc, args interface{}, next api.JsonRpcHandler) (interface{}, *api.JsonRpcError) {
+		release, err := l.Acquire(ctx.Context())
+		if err != nil {
+			return nil, interceptor.Shed(desc.Service+"."+desc.Method, name, err)
+		}
+		defer release()
+		return next(ctx, args)
+	}
+}
@@ -0,0 +1,123 @@
+package jsonrpc
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/interceptor"
+	"github.com/BabySid/gorpc/internal/log"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLog(nil)
+	os.Exit(m.Run())
+}
+
+type testContext struct {
+	ctx context.Context
+}
+
+func (c *testContext) CtxID() interface{}              { return "test" }
+func (c *testContext) ClientIP() string                { return "127.0.0.1" }
+func (c *testContext) PeerIdentity() *api.PeerIdentity { return nil }
+func (c *testContext) WithValue(string, any)           {}
+func (c *testContext) Value(string) (any, bool)        { return nil, false }
+func (c *testContext) Context() context.Context        { return c.ctx }
+
+func TestWithTimeout(t *testing.T) {
+	desc := &api.MethodDesc{Service: "rpc", Method: "Add"}
+	fail := api.NewJsonRpcErrFromCode(api.InvalidParams, "bad params")
+
+	tests := []struct {
+		name      string
+		handler   api.JsonRpcHandler
+		want      interface{}
+		wantCode  int
+		wantPanic bool
+	}{
+		{
+			name: "in time",
+			handler: func(api.Context, interface{}) (interface{}, *api.JsonRpcError) {
+				return 1, nil
+			},
+			want: 1,
+		},
+		{
+			name: "error in time",
+			handler: func(api.Context, interface{}) (interface{}, *api.JsonRpcError) {
+				return nil, fail
+			},
+			wantCode: api.InvalidParams,
+		},
+		{
+			name: "sees the deadline",
+			handler: func(ctx api.Context, _ interface{}) (interface{}, *api.JsonRpcError) {
+				<-ctx.Context().Done()
+				return 1, nil
+			},
+			wantCode: api.DeadlineExceeded,
+		},
+		{
+			name: "hangs",
+			handler: func(api.Context, interface{}) (interface{}, *api.JsonRpcError) {
+				time.Sleep(time.Second)
+				return 1, nil
+			},
+			wantCode: api.DeadlineExceeded,
+		},
+		{
+			name: "panics after the timeout",
+			handler: func(api.Context, interface{}) (interface{}, *api.JsonRpcError) {
+				time.Sleep(100 * time.Millisecond)
+				panic("boom")
+			},
+			wantCode: api.DeadlineExceeded,
+		},
+		{
+			name: "panics on the caller",
+			handler: func(api.Context, interface{}) (interface{}, *api.JsonRpcError) {
+				panic("boom")
+			},
+			wantPanic: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if (r != nil) != tt.wantPanic {
+					t.Errorf("recover() = %v, wantPanic %v", r, tt.wantPanic)
+				}
+				// the panic keeps the stack of the handler
+				if p, ok := r.(*interceptor.Panic); tt.wantPanic && (!ok || p.Value != "boom" || len(p.Stack) == 0) {
+					t.Errorf("recover() = %v, want the panic of the handler", r)
+				}
+			}()
+
+			const timeout = 20 * time.Millisecond
+			h := withTimeout(tt.handler, desc, timeout)
+			begin := time.Now()
+			got, err := h(&testContext{ctx: context.Background()}, nil)
+			// the caller does not wait for the handler
+			if cost := time.Since(begin); cost > timeout+50*time.Millisecond {
+				t.Errorf("handler() took %v, want about %v", cost, timeout)
+			}
+			if got != tt.want {
+				t.Errorf("handler() = %v, want %v", got, tt.want)
+			}
+			if tt.wantCode == 0 {
+				if err != nil {
+					t.Errorf("handler() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Code != tt.wantCode {
+				t.Errorf("handler() error = %v, want code %d", err, tt.wantCode)
+			}
+		})
+	}
+}
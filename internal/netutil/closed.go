@@ -0,0 +1,18 @@
+package netutil
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/soheilhy/cmux"
+)
+
+// IsClosed reports whether err is returned by a server or listener
+// because it was closed on purpose.
+func IsClosed(err error) bool {
+	return errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, http.ErrServerClosed) ||
+		errors.Is(err, cmux.ErrServerClosed) ||
+		errors.Is(err, cmux.ErrListenerClosed)
+}
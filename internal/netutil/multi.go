@@ -0,0 +1,120 @@
+package netutil
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/BabySid/gorpc/internal/log"
+)
+
+type acceptResult struct {
+	conn net.Conn
+	err  error
+}
+
+// multiListener accepts connections from several listeners,
+// so that they can be served by one cmux.
+type multiListener struct {
+	lns   []net.Listener
+	connc chan acceptResult
+	done  chan struct{}
+	once  sync.Once
+}
+
+// Multi merges lns into one listener. The address of the first one is
+// the address of the merged listener. An error of any of lns, other than
+// running out of file descriptors or alike, fails the merged listener.
+func Multi(lns ...net.Listener) net.Listener {
+	if len(lns) == 1 {
+		return lns[0]
+	}
+
+	m := &multiListener{
+		lns:   lns,
+		connc: make(chan acceptResult),
+		done:  make(chan struct{}),
+	}
+	for _, ln := range lns {
+		go m.accept(ln)
+	}
+	return m
+}
+
+func (m *multiListener) accept(ln net.Listener) {
+	var delay time.Duration
+	for {
+		c, err := ln.Accept()
+		if err != nil && retryable(err) {
+			delay = min(max(2*delay, 5*time.Millisecond), time.Second)
+			log.DefaultLog.Warn("accept failed, retrying", slog.String("addr", ln.Addr().String()),
+				slog.Duration("delay", delay), slog.Any("err", err))
+			select {
+			case <-time.After(delay):
+				continue
+			case <-m.done:
+				return
+			}
+		}
+		delay = 0
+
+		if err != nil {
+			// it is no longer a net.Error, which cmux would take as temporary
+			// and keep serving without this listener
+			err = fmt.Errorf("accept %s: %w", ln.Addr(), err)
+		}
+		select {
+		case m.connc <- acceptResult{conn: c, err: err}:
+		case <-m.done:
+			if c != nil {
+				_ = c.Close()
+			}
+			return
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// retryable reports whether err is caused by running out of resources,
+// which may be released later, or by a connection aborted before it is
+// accepted. The listener keeps accepting after such errors.
+func retryable(err error) bool {
+	return errors.Is(err, syscall.EMFILE) ||
+		errors.Is(err, syscall.ENFILE) ||
+		errors.Is(err, syscall.ENOBUFS) ||
+		errors.Is(err, syscall.ENOMEM) ||
+		errors.Is(err, syscall.ECONNABORTED)
+}
+
+func (m *multiListener) Accept() (net.Conn, error) {
+	select {
+	case res := <-m.connc:
+		return res.conn, res.err
+	case <-m.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (m *multiListener) Close() error {
+	var err error
+	m.once.Do(func() {
+		close(m.done)
+		for _, ln := range m.lns {
+			if e := ln.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+	})
+	return err
+}
+
+func (m *multiListener) Addr() net.Addr {
+	return m.lns[0].Addr()
+}
@@ -0,0 +1,79 @@
+package netutil
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// PipeAddr is the address of both ends of the connections of a PipeListener.
+type PipeAddr struct{}
+
+func (PipeAddr) Network() string {
+	return "pipe"
+}
+
+func (PipeAddr) String() string {
+	return "pipe"
+}
+
+// PipeListener is an in-memory listener, whose connections are made by net.Pipe.
+type PipeListener struct {
+	connc chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func ListenPipe() *PipeListener {
+	return &PipeListener{
+		connc: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+// DialContext connects to l. It blocks until the connection is accepted.
+func (l *PipeListener) DialContext(ctx context.Context) (net.Conn, error) {
+	client, server := net.Pipe()
+	select {
+	case l.connc <- &pipeConn{Conn: server}:
+		return &pipeConn{Conn: client}, nil
+	case <-l.done:
+		_, _ = client.Close(), server.Close()
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		_, _ = client.Close(), server.Close()
+		return nil, ctx.Err()
+	}
+}
+
+func (l *PipeListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.connc:
+		return c, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *PipeListener) Close() error {
+	l.once.Do(func() {
+		close(l.done)
+	})
+	return nil
+}
+
+func (l *PipeListener) Addr() net.Addr {
+	return PipeAddr{}
+}
+
+type pipeConn struct {
+	net.Conn
+}
+
+func (c *pipeConn) LocalAddr() net.Addr {
+	return PipeAddr{}
+}
+
+func (c *pipeConn) RemoteAddr() net.Addr {
+	return PipeAddr{}
+}
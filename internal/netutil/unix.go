@@ -0,0 +1,95 @@
+package netutil
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	UnixScheme          = "unix"
+	DefaultUnixSockMode = 0o660
+
+	unixSockSuffix = ".sock"
+)
+
+// ListenUnix listens on addr like unix:///var/run/app.sock and sets the
+// permission of the socket file to mode. A stale socket file left by a
+// crashed process is removed, while a socket which is still accepting
+// connections is reported as in use.
+func ListenUnix(addr string, mode os.FileMode) (net.Listener, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != UnixScheme || u.Path == "" {
+		return nil, fmt.Errorf("invalid unix address %q", addr)
+	}
+
+	if err = removeStaleSocket(u.Path); err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", u.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == 0 {
+		mode = DefaultUnixSockMode
+	}
+	if err = os.Chmod(u.Path, mode); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+func removeStaleSocket(path string) error {
+	fi, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a unix socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("unix socket %s is in use", path)
+	}
+	return os.Remove(path)
+}
+
+// SplitUnixURL splits urls like http+unix:///var/run/app.sock/_jsonrpc_ into
+// the socket path /var/run/app.sock and the url http://unix/_jsonrpc_ which is
+// requested over that socket. The socket path ends with the first element
+// having the .sock suffix.
+func SplitUnixURL(rawUrl string) (string, string, error) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", "", err
+	}
+	scheme, ok := strings.CutSuffix(u.Scheme, "+"+UnixScheme)
+	if !ok {
+		return "", "", fmt.Errorf("%q is not a unix socket url", rawUrl)
+	}
+
+	sockPath, path := u.Path, ""
+	if i := strings.Index(u.Path, unixSockSuffix+"/"); i >= 0 {
+		sockPath, path = u.Path[:i+len(unixSockSuffix)], u.Path[i+len(unixSockSuffix):]
+	} else if !strings.HasSuffix(u.Path, unixSockSuffix) {
+		return "", "", fmt.Errorf("socket path of %q must have the %s suffix", rawUrl, unixSockSuffix)
+	}
+
+	target := url.URL{Scheme: scheme, Host: UnixScheme, Path: path, RawQuery: u.RawQuery}
+	return sockPath, target.String(), nil
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/BabySid/gorpc/api"
+)
+
+// Bucket is a token bucket of api.RateLimit.
+type Bucket struct {
+	rate  float64
+	burst float64
+
+	mux    sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func NewBucket(limit api.RateLimit) *Bucket {
+	burst := float64(limit.Burst)
+	if burst <= 0 {
+		burst = math.Max(1, limit.Rate)
+	}
+	return &Bucket{rate: limit.Rate, burst: burst, tokens: burst}
+}
+
+// Take takes a token at now. If there is none, it returns false and how long
+// it takes to refill one.
+func (b *Bucket) Take(now time.Time) (bool, time.Duration) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if b.rate <= 0 {
+		return false, time.Duration(math.MaxInt64)
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// Refund puts back a token taken by Take, e.g. when the call is refused by
+// another bucket.
+func (b *Bucket) Refund() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
+
+// full reports whether the bucket is refilled at now, so it is safe to drop it.
+func (b *Bucket) full(now time.Time) bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.refill(now)
+	return b.tokens >= b.burst
+}
+
+func (b *Bucket) refill(now time.Time) {
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+}
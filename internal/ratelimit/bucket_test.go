@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BabySid/gorpc/api"
+)
+
+func TestBucketTake(t *testing.T) {
+	begin := time.Now()
+	type take struct {
+		after time.Duration
+		// refund calls Refund instead of Take
+		refund   bool
+		wantOK   bool
+		wantWait time.Duration
+	}
+	tests := []struct {
+		name  string
+		limit api.RateLimit
+		takes []take
+	}{
+		{
+			name:  "burst then refill",
+			limit: api.RateLimit{Rate: 2, Burst: 2},
+			takes: []take{
+				{wantOK: true},
+				{wantOK: true},
+				{wantWait: 500 * time.Millisecond},
+				{after: 250 * time.Millisecond, wantWait: 250 * time.Millisecond},
+				{after: 500 * time.Millisecond, wantOK: true},
+			},
+		},
+		{
+			name:  "burst defaults to rate",
+			limit: api.RateLimit{Rate: 3},
+			takes: []take{{wantOK: true}, {wantOK: true}, {wantOK: true}, {wantWait: time.Second / 3}},
+		},
+		{
+			name:  "at least one token",
+			limit: api.RateLimit{Rate: 0.5},
+			takes: []take{{wantOK: true}, {wantWait: 2 * time.Second}, {after: 2 * time.Second, wantOK: true}},
+		},
+		{
+			name:  "full after a long idle",
+			limit: api.RateLimit{Rate: 1, Burst: 2},
+			takes: []take{{wantOK: true}, {wantOK: true}, {after: time.Hour, wantOK: true}, {after: time.Hour, wantOK: true},
+				{after: time.Hour, wantWait: time.Second}},
+		},
+		{
+			name:  "refund up to the burst",
+			limit: api.RateLimit{Rate: 1, Burst: 1},
+			takes: []take{{wantOK: true}, {wantWait: time.Second}, {refund: true}, {wantOK: true},
+				{refund: true}, {refund: true}, {wantOK: true}, {wantWait: time.Second}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBucket(tt.limit)
+			for i, tk := range tt.takes {
+				if tk.refund {
+					b.Refund()
+					continue
+				}
+				ok, wait := b.Take(begin.Add(tk.after))
+				if ok != tk.wantOK || (!ok && absDuration(wait-tk.wantWait) > time.Millisecond) {
+					t.Errorf("take %d: Take() = %v, %v, want %v, %v", i, ok, wait, tk.wantOK, tk.wantWait)
+				}
+			}
+		})
+	}
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
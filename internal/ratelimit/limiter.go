@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"path"
+	"sync"
+	"time"
+
+	"github.com/BabySid/gorpc/api"
+)
+
+const (
+	ScopeCaller = "caller"
+	ScopeMethod = "method"
+	ScopeGlobal = "global"
+)
+
+// sweepInterval is how often the full buckets of callers and methods are dropped.
+const sweepInterval = time.Minute
+
+// Limiter applies api.RateLimitOption.
+type Limiter struct {
+	opt    *api.RateLimitOption
+	global *Bucket
+
+	mux       sync.Mutex
+	callers   map[string]*Bucket
+	methods   map[string]*Bucket
+	lastSweep time.Time
+}
+
+func NewLimiter(opt *api.RateLimitOption) *Limiter {
+	l := &Limiter{
+		opt:       opt,
+		callers:   make(map[string]*Bucket),
+		methods:   make(map[string]*Bucket),
+		lastSweep: time.Now(),
+	}
+	if opt.Global != nil {
+		l.global = NewBucket(*opt.Global)
+	}
+	return l
+}
+
+// Allow takes a token of caller, method and the server in order. If one is
+// exhausted, it returns its scope and how long to wait, and the tokens taken
+// from the scopes before are refunded, so a refused call costs nothing.
+func (l *Limiter) Allow(caller string, method string) (bool, string, time.Duration) {
+	now := time.Now()
+	l.sweep(now)
+
+	var scopes []scope
+	if l.opt.Caller != nil {
+		scopes = append(scopes, scope{ScopeCaller, l.bucket(l.callers, caller, *l.opt.Caller)})
+	}
+	if m, ok := l.methodLimit(method); ok {
+		scopes = append(scopes, scope{ScopeMethod, l.bucket(l.methods, m.Pattern, m.RateLimit)})
+	}
+	if l.global != nil {
+		scopes = append(scopes, scope{ScopeGlobal, l.global})
+	}
+
+	for i, s := range scopes {
+		if ok, wait := s.bucket.Take(now); !ok {
+			for _, taken := range scopes[:i] {
+				taken.bucket.Refund()
+			}
+			return false, s.name, wait
+		}
+	}
+	return true, "", 0
+}
+
+type scope struct {
+	name   string
+	bucket *Bucket
+}
+
+// methodLimit returns the first limit matching method. The methods matching
+// it share one bucket, which is keyed by its pattern.
+func (l *Limiter) methodLimit(method string) (api.MethodRateLimit, bool) {
+	for _, m := range l.opt.Methods {
+		if matched, _ := path.Match(m.Pattern, method); matched {
+			return m, true
+		}
+	}
+	return api.MethodRateLimit{}, false
+}
+
+func (l *Limiter) bucket(buckets map[string]*Bucket, key string, limit api.RateLimit) *Bucket {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	b, ok := buckets[key]
+	if !ok {
+		b = NewBucket(limit)
+		buckets[key] = b
+	}
+	return b
+}
+
+// sweep drops the full buckets, which behave the same as new ones, so that
+// the buckets of the callers gone do not pile up.
+func (l *Limiter) sweep(now time.Time) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for _, buckets := range []map[string]*Bucket{l.callers, l.methods} {
+		for key, b := range buckets {
+			if b.full(now) {
+				delete(buckets, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"testing"
+
+	"github.com/BabySid/gorpc/api"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	// the rates are low enough that no token is refilled during the test
+	one := &api.RateLimit{Rate: 0.001, Burst: 1}
+	two := &api.RateLimit{Rate: 0.001, Burst: 2}
+
+	type call struct {
+		caller    string
+		method    string
+		wantOK    bool
+		wantScope string
+	}
+	tests := []struct {
+		name  string
+		opt   *api.RateLimitOption
+		calls []call
+	}{
+		{
+			name:  "no limits",
+			opt:   &api.RateLimitOption{},
+			calls: []call{{caller: "a", method: "m", wantOK: true}, {caller: "a", method: "m", wantOK: true}},
+		},
+		{
+			name: "global",
+			opt:  &api.RateLimitOption{Global: two},
+			calls: []call{
+				{caller: "a", method: "m", wantOK: true},
+				{caller: "b", method: "n", wantOK: true},
+				{caller: "c", method: "o", wantScope: ScopeGlobal},
+			},
+		},
+		{
+			name: "each caller",
+			opt:  &api.RateLimitOption{Caller: one},
+			calls: []call{
+				{caller: "a", method: "m", wantOK: true},
+				{caller: "b", method: "m", wantOK: true},
+				{caller: "a", method: "n", wantScope: ScopeCaller},
+			},
+		},
+		{
+			name: "methods of a pattern share a bucket",
+			opt:  &api.RateLimitOption{Methods: []api.MethodRateLimit{{Pattern: "admin.*", RateLimit: *two}}},
+			calls: []call{
+				{caller: "a", method: "admin.Add", wantOK: true},
+				{caller: "b", method: "admin.Del", wantOK: true},
+				{caller: "c", method: "admin.Get", wantScope: ScopeMethod},
+				{caller: "c", method: "rpc.Get", wantOK: true},
+			},
+		},
+		{
+			name: "first matching pattern",
+			opt: &api.RateLimitOption{Methods: []api.MethodRateLimit{
+				{Pattern: "rpc.Add", RateLimit: *one},
+				{Pattern: "rpc.*", RateLimit: *two},
+			}},
+			calls: []call{
+				{caller: "a", method: "rpc.Add", wantOK: true},
+				{caller: "a", method: "rpc.Add", wantScope: ScopeMethod},
+				{caller: "a", method: "rpc.Sub", wantOK: true},
+				{caller: "a", method: "rpc.Mul", wantOK: true},
+				{caller: "a", method: "rpc.Div", wantScope: ScopeMethod},
+			},
+		},
+		{
+			name: "caller before method before global",
+			opt:  &api.RateLimitOption{Caller: one, Methods: []api.MethodRateLimit{{Pattern: "m", RateLimit: *one}}, Global: two},
+			calls: []call{
+				{caller: "a", method: "m", wantOK: true},
+				{caller: "a", method: "m", wantScope: ScopeCaller},
+				{caller: "b", method: "m", wantScope: ScopeMethod},
+				{caller: "c", method: "n", wantOK: true},
+				{caller: "d", method: "n", wantScope: ScopeGlobal},
+			},
+		},
+		{
+			name: "refused calls keep the caller tokens",
+			opt:  &api.RateLimitOption{Caller: two, Methods: []api.MethodRateLimit{{Pattern: "m", RateLimit: *one}}},
+			calls: []call{
+				{caller: "a", method: "m", wantOK: true},
+				{caller: "a", method: "m", wantScope: ScopeMethod},
+				{caller: "a", method: "m", wantScope: ScopeMethod},
+				{caller: "a", method: "n", wantOK: true},
+				{caller: "a", method: "n", wantScope: ScopeCaller},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLimiter(tt.opt)
+			for i, c := range tt.calls {
+				ok, scope, _ := l.Allow(c.caller, c.method)
+				if ok != c.wantOK || scope != c.wantScope {
+					t.Errorf("call %d: Allow(%s, %s) = %v, %q, want %v, %q", i, c.caller, c.method, ok, scope, c.wantOK, c.wantScope)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiterRefund(t *testing.T) {
+	two := api.RateLimit{Rate: 0.001, Burst: 2}
+	l := NewLimiter(&api.RateLimitOption{
+		Caller:  &two,
+		Methods: []api.MethodRateLimit{{Pattern: "m", RateLimit: two}},
+		Global:  &api.RateLimit{Rate: 0.001, Burst: 1},
+	})
+
+	if ok, _, _ := l.Allow("a", "m"); !ok {
+		t.Fatal("Allow() of the first call = false")
+	}
+	for i := 0; i < 3; i++ {
+		if ok, scope, _ := l.Allow("a", "m"); ok || scope != ScopeGlobal {
+			t.Fatalf("Allow() = %v, %q, want refused by %q", ok, scope, ScopeGlobal)
+		}
+	}
+
+	// only the first call took a token of the caller and the method
+	for name, b := range map[string]*Bucket{"caller": l.callers["a"], "method": l.methods["m"]} {
+		b.mux.Lock()
+		tokens := b.tokens
+		b.mux.Unlock()
+		if tokens < 1 || tokens >= 1.5 {
+			t.Errorf("%s bucket has %.2f tokens, want 1", name, tokens)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package runfile
+
+import (
+	"os"
+	"syscall"
+)
+
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+}
@@ -0,0 +1,125 @@
+package runfile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	fileMode   = 0o644
+	lockSuffix = ".lock"
+)
+
+// PidFile holds an advisory lock on <path>.lock as long as the process
+// runs, so that a second copy refuses to start. The lock is not taken on
+// the pid file itself, which is replaced by a rename on every write.
+type PidFile struct {
+	path string
+	lock *os.File
+	pid  int
+}
+
+func LockPidFile(path string) (*PidFile, error) {
+	f, err := os.OpenFile(path+lockSuffix, os.O_RDWR|os.O_CREATE, fileMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = lockFile(f); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("pid file %s is locked by another process: %w", path, err)
+	}
+	return &PidFile{path: path, lock: f}, nil
+}
+
+// LockPidFileWait is like LockPidFile, but keeps trying until timeout.
+// It is used when the lock is handed over by another process.
+func LockPidFileWait(path string, timeout time.Duration) (*PidFile, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		p, err := LockPidFile(path)
+		if err == nil || time.Now().After(deadline) {
+			return p, err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func (p *PidFile) Path() string {
+	return p.path
+}
+
+// Write replaces the content with pid by WriteFile, so that readers
+// never see a partially written pid.
+func (p *PidFile) Write(pid int) error {
+	if err := WriteFile(p.path, []byte(strconv.Itoa(pid))); err != nil {
+		return err
+	}
+	p.pid = pid
+	return nil
+}
+
+// Close drops the lock and leaves the file to the next owner.
+func (p *PidFile) Close() error {
+	return p.lock.Close()
+}
+
+// Release removes the pid file if it still names the pid of Write, and then
+// drops the lock. The file is checked and removed under the lock, so that the
+// next owner, which writes its pid once it holds the lock, is never removed.
+// The lock file is kept, or two processes could lock different files of it.
+func (p *PidFile) Release() error {
+	var err error
+	if p.pid != 0 {
+		err = p.removeOwn()
+	}
+	return errors.Join(err, p.lock.Close())
+}
+
+func (p *PidFile) removeOwn() error {
+	data, err := os.ReadFile(p.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(data)) != strconv.Itoa(p.pid) {
+		return nil
+	}
+	return os.Remove(p.path)
+}
+
+// WriteFile writes data to a temporary file in the same directory and renames it
+// to path, so that readers never see a partially written file.
+func WriteFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), fileMode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,76 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package runfile
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRelease(t *testing.T) {
+	tests := []struct {
+		name string
+		// pid is written by the owner unless it is zero
+		pid int
+		// content replaces the pid file before Release unless it is empty
+		content  string
+		remove   bool
+		wantFile string
+	}{
+		{name: "own pid", pid: 1234},
+		{name: "replaced by the next owner", pid: 1234, content: "5678", wantFile: "5678"},
+		{name: "never written", content: "5678", wantFile: "5678"},
+		{name: "removed", pid: 1234, remove: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.pid")
+			p, err := LockPidFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LockPidFile(path); err == nil {
+				t.Fatal("LockPidFile() succeeds while it is locked")
+			}
+
+			if tt.pid != 0 {
+				if err := p.Write(tt.pid); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.content != "" {
+				if err := WriteFile(path, []byte(tt.content)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.remove {
+				if err := os.Remove(path); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := p.Release(); err != nil {
+				t.Fatalf("Release() = %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if tt.wantFile == "" {
+				if !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("pid file = %q, %v, want removed", data, err)
+				}
+			} else if string(data) != tt.wantFile {
+				t.Errorf("pid file = %q, %v, want %q", data, err, tt.wantFile)
+			}
+
+			// the lock is dropped
+			next, err := LockPidFile(path)
+			if err != nil {
+				t.Fatalf("LockPidFile() after Release = %v", err)
+			}
+			_ = next.Close()
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package upgrade
+
+import (
+	"errors"
+	"os"
+)
+
+const (
+	// EnvListenFds is the number of listeners inherited by the new process.
+	// They are passed as the files starting from fd 3 in the order of
+	// Addr, UnixAddrs and AdminAddr.
+	EnvListenFds = "GORPC_LISTEN_FDS"
+	// EnvReadyFd is the pipe which is closed once the new process is ready.
+	EnvReadyFd = "GORPC_READY_FD"
+
+	firstListenFd = 3
+)
+
+var errUnsupported = errors.New("upgrade is only supported on linux")
+
+// Inherited tells whether the process is started by Start.
+func Inherited() bool {
+	return os.Getenv(EnvListenFds) != ""
+}
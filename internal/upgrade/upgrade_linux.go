@@ -0,0 +1,156 @@
+//go:build linux
+
+package upgrade
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Signal asks the server to upgrade its binary.
+var Signal os.Signal = syscall.SIGUSR2
+
+type filer interface {
+	File() (*os.File, error)
+}
+
+// Start execs the binary of os.Args[0] with the same arguments and passes lns to
+// it. It returns the pid of the new process once that process is ready.
+func Start(lns []net.Listener, timeout time.Duration) (int, error) {
+	files := make([]*os.File, 0, len(lns)+1)
+	closeFiles := func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+		files = nil
+	}
+	defer closeFiles()
+
+	for _, ln := range lns {
+		fl, ok := ln.(filer)
+		if !ok {
+			return 0, fmt.Errorf("listener %s can not be inherited", ln.Addr())
+		}
+		f, err := fl.File()
+		if err != nil {
+			return 0, err
+		}
+		files = append(files, f)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	files = append(files, w)
+
+	path, err := exec.LookPath(os.Args[0])
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := exec.Command(path, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(environ(),
+		EnvListenFds+"="+strconv.Itoa(len(lns)),
+		EnvReadyFd+"="+strconv.Itoa(firstListenFd+len(lns)))
+	if err = cmd.Start(); err != nil {
+		return 0, err
+	}
+	// the new process owns the copies now, and r gets EOF once it closes w
+	closeFiles()
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	ready := make(chan error, 1)
+	go func() {
+		var b [1]byte
+		_, err := r.Read(b[:])
+		ready <- err
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err = <-ready:
+		if err != nil {
+			_ = cmd.Process.Kill()
+			return 0, fmt.Errorf("new process is not ready: %w", err)
+		}
+		return cmd.Process.Pid, nil
+	case err = <-exited:
+		return 0, fmt.Errorf("new process exited: %v", err)
+	case <-timer.C:
+		_ = cmd.Process.Kill()
+		return 0, errors.New("new process is not ready in time")
+	}
+}
+
+func environ() []string {
+	env := make([]string, 0, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, EnvListenFds+"=") || strings.HasPrefix(kv, EnvReadyFd+"=") {
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
+
+// Listeners returns the listeners passed by Start.
+func Listeners() ([]net.Listener, error) {
+	n, err := strconv.Atoi(os.Getenv(EnvListenFds))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", EnvListenFds, err)
+	}
+	_ = os.Unsetenv(EnvListenFds)
+
+	lns := make([]net.Listener, 0, n)
+	for i := 0; i < n; i++ {
+		f := os.NewFile(uintptr(firstListenFd+i), "listener"+strconv.Itoa(i))
+		ln, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			for _, l := range lns {
+				_ = l.Close()
+			}
+			return nil, err
+		}
+		if ul, ok := ln.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(true)
+		}
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
+
+// NotifyReady tells the old process to begin draining.
+func NotifyReady() error {
+	fd, err := strconv.Atoi(os.Getenv(EnvReadyFd))
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", EnvReadyFd, err)
+	}
+	_ = os.Unsetenv(EnvReadyFd)
+
+	f := os.NewFile(uintptr(fd), "ready")
+	_, err = f.Write([]byte{1})
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	return err
+}
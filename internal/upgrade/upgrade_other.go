@@ -0,0 +1,24 @@
+//go:build !linux
+
+package upgrade
+
+import (
+	"net"
+	"os"
+	"time"
+)
+
+// Signal is nil where upgrade is not supported.
+var Signal os.Signal
+
+func Start([]net.Listener, time.Duration) (int, error) {
+	return 0, errUnsupported
+}
+
+func Listeners() ([]net.Listener, error) {
+	return nil, errUnsupported
+}
+
+func NotifyReady() error {
+	return errUnsupported
+}
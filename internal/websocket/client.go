@@ -1,13 +1,16 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/BabySid/gobase"
 	"github.com/BabySid/gorpc/api"
 	"github.com/BabySid/gorpc/internal/jsonrpc"
+	"github.com/BabySid/gorpc/internal/netutil"
 	"github.com/gorilla/websocket"
+	"net"
 	"net/http"
 	"reflect"
 	"strings"
@@ -64,6 +67,18 @@ func Dial(rawUrl string, opt api.ClientOption) (*Client, error) {
 		ReadBufferSize:  wsReadBuffer,
 		WriteBufferSize: wsWriteBuffer,
 		WriteBufferPool: wsBufferPool,
+		TLSClientConfig: opt.TLSConfig,
+	}
+	if strings.HasPrefix(rawUrl, "ws+unix:") {
+		sockPath, target, err := netutil.SplitUnixURL(rawUrl)
+		if err != nil {
+			return nil, err
+		}
+		rawUrl = target
+		dialer.NetDialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", sockPath)
+		}
 	}
 	conn, resp, err := dialer.Dial(rawUrl, http.Header{})
 	if err != nil {
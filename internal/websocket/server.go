@@ -4,11 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"sync"
 	"time"
 
 	"github.com/BabySid/gobase"
 	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/ctx"
+	"github.com/BabySid/gorpc/internal/interceptor"
 	"github.com/BabySid/gorpc/internal/jsonrpc"
 	"github.com/BabySid/gorpc/internal/log"
 	"github.com/gin-gonic/gin"
@@ -42,6 +45,12 @@ type wsOption struct {
 
 	rawHandle   api.RawWsHandle
 	rawNotifier *rawNotifier
+
+	interceptor api.Interceptor
+	principal   *api.Principal
+	checkOrigin func(r *http.Request) bool
+	readLimit   int64
+	processing  *ctx.Processing
 }
 
 type WsOption func(opt *wsOption)
@@ -60,18 +69,58 @@ func WithRawHandle(handle api.RawWsHandle) WsOption {
 	}
 }
 
-func NewServer(ctx *gin.Context, opts ...WsOption) (*Server, error) {
-	gobase.True(len(opts) > 0)
+// WithInterceptor wraps the raw handle. Json-rpc is intercepted by the rpc server itself.
+func WithInterceptor(i api.Interceptor) WsOption {
+	return func(opt *wsOption) {
+		opt.interceptor = i
+	}
+}
 
-	conn, err := upGrader.Upgrade(ctx.Writer, ctx.Request, nil)
-	if err != nil {
-		return nil, err
+// WithPrincipal is the caller authenticated at the handshake, it is put in the context of every message.
+func WithPrincipal(p *api.Principal) WsOption {
+	return func(opt *wsOption) {
+		opt.principal = p
+	}
+}
+
+// WithCheckOrigin replaces the check of the upgrader, which only allows the same origin.
+func WithCheckOrigin(check func(r *http.Request) bool) WsOption {
+	return func(opt *wsOption) {
+		opt.checkOrigin = check
+	}
+}
+
+// WithReadLimit bounds the messages read, wsMessageSizeLimit if n is not positive.
+func WithReadLimit(n int64) WsOption {
+	return func(opt *wsOption) {
+		opt.readLimit = n
+	}
+}
+
+// WithProcessing counts the messages being handled in p, which is required.
+func WithProcessing(p *ctx.Processing) WsOption {
+	return func(opt *wsOption) {
+		opt.processing = p
 	}
+}
+
+func NewServer(ctx *gin.Context, opts ...WsOption) (*Server, error) {
+	gobase.True(len(opts) > 0)
 
 	s := Server{}
 	for _, opt := range opts {
 		opt(&s.option)
 	}
+	gobase.True(s.option.processing != nil)
+
+	upgrader := upGrader
+	if s.option.checkOrigin != nil {
+		upgrader.CheckOrigin = s.option.checkOrigin
+	}
+	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		return nil, err
+	}
 
 	s.conn = conn
 	s.readErr = make(chan error)
@@ -79,7 +128,11 @@ func NewServer(ctx *gin.Context, opts ...WsOption) (*Server, error) {
 	s.closeCh = make(chan struct{})
 	s.pingReset = make(chan struct{})
 	s.serverErr = make(chan error, 1)
-	s.conn.SetReadLimit(wsMessageSizeLimit)
+	readLimit := int64(wsMessageSizeLimit)
+	if s.option.readLimit > 0 {
+		readLimit = s.option.readLimit
+	}
+	s.conn.SetReadLimit(readLimit)
 	s.conn.SetPongHandler(func(v string) error {
 		_ = s.conn.SetReadDeadline(time.Time{})
 		return nil
@@ -145,6 +198,21 @@ func (s *Server) Close() {
 	log.DefaultLog.Info("close from websocket", slog.String("clientIP", s.clientIP))
 }
 
+// GoingAway asks the peer to close the session with CloseGoingAway.
+// The connection is closed anyway once wsCloseGracePeriod elapses.
+func (s *Server) GoingAway() {
+	msg := ws.FormatCloseMessage(ws.CloseGoingAway, "server is shutting down")
+	_ = s.conn.WriteControl(ws.CloseMessage, msg, time.Now().Add(wsWriteTimeout))
+	time.AfterFunc(wsCloseGracePeriod, func() {
+		_ = s.conn.Close()
+	})
+}
+
+// Abort closes the connection at once, Run returns as the read fails.
+func (s *Server) Abort() {
+	_ = s.conn.Close()
+}
+
 func (s *Server) pingLoop() {
 	timer := time.NewTimer(wsPingInterval)
 	defer s.wg.Done()
@@ -204,24 +272,33 @@ func (s *Server) read() {
 	}
 }
 
-func (s *Server) handleRaw(msg api.WSMessage) error {
+// handleRaw calls the raw handle with msg. A panic fails this message only, not the session.
+func (s *Server) handleRaw(msg api.WSMessage) (err error) {
 	context := newWSContext("RawWs", uuid.New().String(), len(msg.Data), s)
+	context.WithValue(api.RawWSNotifierKey, s.option.rawNotifier)
+
+	info := &api.CallInfo{Transport: api.TransportRawWs, Method: s.ctx.Request.URL.Path, Request: msg}
 	defer func() {
-		context.EndRequest(api.Success)
+		context.EndRequest(interceptor.Code(api.TransportRawWs, err))
 	}()
+	defer interceptor.Recover(context, info.Method, &err)
 
-	context.WithValue(api.RawWSNotifierKey, s.option.rawNotifier)
-	return s.option.rawHandle(context, msg)
+	_, err = interceptor.Invoke(s.option.interceptor, context, info, func(ctx api.Context) (interface{}, error) {
+		return nil, s.option.rawHandle(ctx, msg)
+	})
+	return err
 }
 
 func (s *Server) handleJsonRpc(msg api.WSMessage) error {
 	context := newWSContext("jsonRpc2", uuid.New().String(), len(msg.Data), s)
+	codes := []int{api.Success}
 	defer func() {
-		context.EndRequest(api.Success)
+		context.EndRequests(codes)
 	}()
 
 	context.WithValue(api.JsonRpcNotifierKey, s.option.rpcNotifier)
 
-	resp := s.option.rpcServer.Call(context, msg.Data)
+	resp := s.option.rpcServer.Call(context, api.TransportWs, msg.Data)
+	codes = jsonrpc.Codes(resp)
 	return s.writeJson(resp)
 }
@@ -14,7 +14,8 @@ const (
 	wsPongTimeout      = 30 * time.Second
 	wsMessageSizeLimit = 10 * 1024 * 1024
 
-	wsWriteTimeout = 10 * time.Second
+	wsWriteTimeout     = 10 * time.Second
+	wsCloseGracePeriod = 5 * time.Second
 )
 
 var (
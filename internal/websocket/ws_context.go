@@ -6,9 +6,9 @@ import (
 
 	"github.com/BabySid/gobase"
 	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/internal/cert"
 	"github.com/BabySid/gorpc/internal/ctx"
 	"github.com/BabySid/gorpc/internal/log"
-	"github.com/BabySid/gorpc/metrics"
 )
 
 var _ api.Context = (*Context)(nil)
@@ -23,20 +23,30 @@ func (ctx *Context) ClientIP() string {
 	return ctx.srv.ctx.ClientIP()
 }
 
+func (ctx *Context) PeerIdentity() *api.PeerIdentity {
+	gobase.True(ctx.srv.ctx != nil)
+	return cert.RequestIdentity(ctx.srv.ctx.Request)
+}
+
 func newWSContext(name string, id interface{}, reqSize int, s *Server) *Context {
-	metrics.ProcessingRequests.WithLabelValues(metrics.GetCluster(), name).Inc()
-	metrics.RealTimeRequestBodySize.WithLabelValues(metrics.GetCluster(), name).Set(float64(reqSize))
+	s.option.processing.BeginRequest(name, reqSize)
 	wsCtx := &Context{
 		srv: s,
 		ContextAdapter: ctx.ContextAdapter{
-			Name:    name,
-			RevTime: time.Now(),
-			ID:      id,
-			KV:      make(map[string]any),
-			Logger:  nil,
+			Name:       name,
+			RevTime:    time.Now(),
+			ID:         id,
+			Ctx:        s.ctx.Request.Context(),
+			KV:         make(map[string]any),
+			Logger:     nil,
+			Processing: s.option.processing,
 		},
 	}
 
+	if s.option.principal != nil {
+		wsCtx.WithValue(api.PrincipalKey, s.option.principal)
+	}
+
 	wsCtx.Logger = log.DefaultLog.WithOut(slog.String("name", wsCtx.Name), slog.Any("ctxID", wsCtx.ID), slog.String("clientIP", wsCtx.ClientIP()))
 	wsCtx.Logger.Info("NewWSContext", slog.Int("reqSize", reqSize))
 	return wsCtx
@@ -0,0 +1,98 @@
+package gorpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/BabySid/gorpc/internal/log"
+	"github.com/BabySid/gorpc/internal/upgrade"
+)
+
+const defaultShutdownTimeout = 30 * time.Second
+
+// RunUntilSignal runs the server until SIGINT or SIGTERM arrives and then shuts
+// it down gracefully within ServerOption.ShutdownTimeout. SIGHUP invokes
+// ServerOption.Reload. Another SIGINT or SIGTERM during the shutdown exits
+// the process immediately. On linux, SIGUSR2 hands the listeners over to a
+// new process by Upgrade before shutting down.
+func (s *Server) RunUntilSignal() error {
+	signals := []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+	if upgrade.Signal != nil {
+		signals = append(signals, upgrade.Signal)
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, signals...)
+	defer signal.Stop(sigCh)
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- s.Run()
+	}()
+
+	for {
+		select {
+		case err := <-runErr:
+			return err
+		case sig := <-sigCh:
+			if sig == syscall.SIGHUP {
+				s.reload()
+				continue
+			}
+			if sig == upgrade.Signal && s.Upgrade() != nil {
+				continue
+			}
+
+			log.DefaultLog.Info("gorpc server recv signal", slog.String("signal", sig.String()))
+			return s.shutdownUntilSignal(sigCh, runErr)
+		}
+	}
+}
+
+func (s *Server) shutdownTimeout() time.Duration {
+	if s.option.ShutdownTimeout <= 0 {
+		return defaultShutdownTimeout
+	}
+	return s.option.ShutdownTimeout
+}
+
+func (s *Server) shutdownUntilSignal(sigCh chan os.Signal, runErr chan error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Shutdown(ctx)
+	}()
+
+	for {
+		select {
+		case err := <-done:
+			return errors.Join(err, <-runErr)
+		case sig := <-sigCh:
+			if sig == syscall.SIGHUP || sig == upgrade.Signal {
+				continue
+			}
+			log.DefaultLog.Warn("gorpc server exit immediately", slog.String("signal", sig.String()))
+			os.Exit(1)
+		}
+	}
+}
+
+func (s *Server) reload() {
+	if s.option.Reload == nil {
+		log.DefaultLog.Info("gorpc server ignore SIGHUP without reload handle")
+		return
+	}
+
+	if err := s.option.Reload(); err != nil {
+		log.DefaultLog.Warn("reload handle failed", slog.Any("err", err))
+		return
+	}
+	log.DefaultLog.Info("gorpc server reloaded")
+}
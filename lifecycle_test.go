@@ -0,0 +1,69 @@
+//go:build !windows
+
+package gorpc
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/BabySid/gorpc/api"
+)
+
+func TestSignalBeforeReady(t *testing.T) {
+	tests := []struct {
+		name string
+		sig  syscall.Signal
+	}{
+		{name: "SIGTERM", sig: syscall.SIGTERM},
+		{name: "SIGINT", sig: syscall.SIGINT},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			afterStop := make(chan struct{})
+			s := NewServer(api.ServerOption{
+				Addr:       "127.0.0.1:0",
+				RuntimeDir: t.TempDir(),
+				// the signal arrives while BeforeRun is running
+				BeforeRun: func() error {
+					if err := syscall.Kill(os.Getpid(), tt.sig); err != nil {
+						return err
+					}
+					time.Sleep(100 * time.Millisecond)
+					return nil
+				},
+				AfterStop: func() error {
+					close(afterStop)
+					return nil
+				},
+			})
+
+			done := make(chan error, 1)
+			go func() {
+				done <- s.RunUntilSignal()
+			}()
+
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("RunUntilSignal() = %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("RunUntilSignal() does not return")
+			}
+
+			select {
+			case <-afterStop:
+			default:
+				t.Error("AfterStop is not called")
+			}
+			select {
+			case <-s.Ready():
+				t.Error("Ready() is closed")
+			default:
+			}
+		})
+	}
+}
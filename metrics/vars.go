@@ -18,7 +18,7 @@ var (
 		"Histogram of latency for requests",
 		[]float64{200.0, 400.0, 600.0, 800.0, 1000.0, 1500.0, 2000.0,
 			2500.0, 3000.0, 5000.0, 10000.0, 20000.0, 30000.0, 45000.0, 60000.0},
-		[]string{"cluster", "method"},
+		[]string{"cluster", "method", "status_code"},
 	)
 
 	RealTimeRequestLatency = NewGaugeWithLabel(
@@ -33,6 +33,36 @@ var (
 		[]string{"cluster", "method"},
 	)
 
+	DeniedRequests = NewCounterWithLabel(
+		"request_denied_total",
+		"Total number of requests denied by authentication or authorization",
+		[]string{"cluster", "method", "reason"},
+	)
+
+	RateLimitedRequests = NewCounterWithLabel(
+		"request_rate_limited_total",
+		"Total number of requests rejected by rate limits",
+		[]string{"cluster", "method", "scope"},
+	)
+
+	ShedRequests = NewCounterWithLabel(
+		"request_shed_total",
+		"Total number of requests shed by concurrency limits",
+		[]string{"cluster", "method", "bulkhead"},
+	)
+
+	ConcurrencyLimit = NewGaugeWithLabel(
+		"request_concurrency_limit",
+		"Current limit of in-flight requests",
+		[]string{"cluster", "bulkhead"},
+	)
+
+	PanicRequests = NewCounterWithLabel(
+		"request_panic_total",
+		"Total number of requests recovered from panics",
+		[]string{"cluster", "method"},
+	)
+
 	cluster = "defaultCluster"
 )
 
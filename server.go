@@ -1,22 +1,36 @@
 package gorpc
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
+	nethttp "net/http"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/BabySid/gorpc/api"
+	"github.com/BabySid/gorpc/codec"
+	"github.com/BabySid/gorpc/internal/cert"
 	"github.com/BabySid/gorpc/internal/grpc"
+	"github.com/BabySid/gorpc/internal/health"
 	"github.com/BabySid/gorpc/internal/http"
+	"github.com/BabySid/gorpc/internal/interceptor"
 	"github.com/BabySid/gorpc/internal/log"
+	"github.com/BabySid/gorpc/internal/netutil"
+	"github.com/BabySid/gorpc/internal/runfile"
+	"github.com/BabySid/gorpc/internal/upgrade"
 	"github.com/BabySid/gorpc/metrics"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/soheilhy/cmux"
 	g "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 type Server struct {
@@ -24,32 +38,64 @@ type Server struct {
 
 	hSvr *http.Server
 	gSvr *grpc.Server
-	mux  cmux.CMux
+	lns  []net.Listener
 
-	pidFile string
+	// runMux guards mux and stopped, since Stop may be called during Run
+	runMux  sync.Mutex
+	mux     cmux.CMux
+	stopped bool
+
+	health *health.Health
+
+	// gwMux serves the grpc services registered by RegisterGrpcGateway
+	gwMux *runtime.ServeMux
+
+	adminLn net.Listener
+
+	pidFile *runfile.PidFile
 	pid     int
 	netFile string
 
-	stopOnce sync.Once
+	// ready is closed once Run is serving
+	ready chan struct{}
+	// errs are the errors of the sub servers
+	errMux sync.Mutex
+	errs   []error
+
+	// beforeRunDone tells AfterStop to be called
+	beforeRunDone atomic.Bool
+	stopOnce      sync.Once
 }
 
 func NewServer(opt api.ServerOption) *Server {
 	log.InitLog(opt.Logger)
 
+	hc := health.New()
+	// the interceptors hold the state shared by all the protocols, like the rate limits
+	i := interceptor.FromOption(opt)
 	s := &Server{
 		option: opt,
-		hSvr:   http.NewServer(opt),
-		gSvr:   grpc.NewServer(),
+		hSvr:   http.NewServer(opt, hc, i),
+		gSvr:   grpc.NewServer(opt, i),
+		health: hc,
+		ready:  make(chan struct{}),
 	}
+	s.hSvr.RegisterChannelz(s.gSvr.ChannelzHandler())
+	s.hSvr.RegisterGrpcWeb(s.gSvr.Handler())
 	return s
 }
 
-func (s *Server) RegisterJsonRPC(name string, receiver interface{}) error {
-	return s.hSvr.RegisterJsonRPC(name, receiver)
+// RegisterJsonRPC registers the exported methods of receiver as the json-rpc
+// methods name.Method. Middlewares can be attached by api.WithJsonRpcMiddleware.
+func (s *Server) RegisterJsonRPC(name string, receiver interface{}, opts ...api.JsonRpcServiceOption) error {
+	return s.hSvr.RegisterJsonRPC(name, receiver, opts...)
 }
 
-func (s *Server) RegisterPath(httpMethod string, path string, handle api.RawHttpHandle) error {
-	return s.hSvr.RegisterPath(httpMethod, path, handle)
+// RegisterPath serves handle on the GET or POST of path. The body of POST can be
+// bounded or streamed by api.WithMaxBodySize and api.WithStreamBody, which are
+// refused for GET.
+func (s *Server) RegisterPath(httpMethod string, path string, handle api.RawHttpHandle, opts ...api.RawPathOption) error {
+	return s.hSvr.RegisterPath(httpMethod, path, handle, opts...)
 }
 
 func (s *Server) RegisterRawWs(handle api.RawWsHandle) error {
@@ -60,67 +106,421 @@ func (s *Server) RegisterGrpc(desc *g.ServiceDesc, impl interface{}) error {
 	return s.gSvr.RegisterGRPC(desc, impl)
 }
 
+// RegisterGrpcGateway serves the google.api.http annotated methods of the grpc
+// services as REST on the same port. register is usually the generated
+// RegisterXxxHandler, and the conn passed to it reaches the grpc server in
+// process. Requests and responses use codec.DefaultProtoMarshal. The calls
+// forwarded see the client ip and the peer identity of the http request.
+// It must be called before Run.
+func (s *Server) RegisterGrpcGateway(register func(ctx context.Context, mux *runtime.ServeMux, conn *g.ClientConn) error) error {
+	conn, err := s.gSvr.InProcessConn()
+	if err != nil {
+		return err
+	}
+	if s.gwMux == nil {
+		s.gwMux = runtime.NewServeMux(
+			runtime.WithMarshalerOption(runtime.MIMEWildcard, codec.DefaultProtoMarshal),
+			runtime.WithMetadata(s.gatewayMetadata))
+		s.hSvr.RegisterGateway(s.gwMux)
+	}
+	return register(context.Background(), s.gwMux, conn)
+}
+
+// gatewayMetadata passes the http client of r to the grpc server,
+// which otherwise only sees the in-process conn of the gateway.
+func (s *Server) gatewayMetadata(_ context.Context, r *nethttp.Request) metadata.MD {
+	return s.gSvr.ForwardPeer(r.Context(), http.GatewayClientIP(r), cert.RequestIdentity(r))
+}
+
+// RegisterHealthChecker adds a checker to the readiness of the server. The
+// checkers are exposed by the BuiltInPathHealthReady endpoint, and by the
+// grpc.health.v1.Health service for both the empty service name and name.
+func (s *Server) RegisterHealthChecker(name string, checker api.HealthChecker) error {
+	return s.health.Register(name, checker)
+}
+
+// GrpcContext returns the api.Context of a grpc call. c is the context
+// passed to the handlers registered by RegisterGrpc, and the api.Context is
+// the one seen by ServerOption.Interceptors.
+func GrpcContext(c context.Context) api.Context {
+	return grpc.FromContext(c)
+}
+
 func (s *Server) Run() error {
 	metrics.InitMonitor(s.option.ClusterName)
 
-	if s.option.BeforeRun != nil {
-		if err := s.option.BeforeRun(); err != nil {
-			log.DefaultLog.Warn("run handle failed", slog.Any("err", err))
-			return err
-		}
+	ln, err := s.prepare()
+	if err != nil {
+		return err
 	}
 
-	ln, err := net.Listen("tcp", s.option.Addr)
-	if err != nil {
+	s.gSvr.RegisterHealth(s.health)
+	mux := cmux.New(ln)
+
+	// the order of matchers is their priority, so register them before serving
+	grpcL := mux.MatchWithWriters(
+		cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc"))
+	httpL := mux.Match(cmux.HTTP1Fast())
+	var h2L net.Listener
+	if s.option.TLS != nil || s.option.EnableH2C {
+		h2L = mux.Match(cmux.HTTP2())
+	}
+
+	if !s.start(mux) {
+		// Stop or Shutdown was called before, e.g. by a signal during BeforeRun
+		s.closeListeners()
+		err = s.afterStop()
+		s.removeRuntimeFiles()
+		log.DefaultLog.Info("gorpc server stopped before running", slog.Int("pid", s.pid), slog.Any("err", err))
 		return err
 	}
 
-	s.mux = cmux.New(ln)
+	if s.adminLn != nil {
+		s.serve("admin", func() error {
+			return s.hSvr.RunAdmin(s.adminLn)
+		})
+	}
+	s.serve("grpc", func() error {
+		return s.gSvr.Run(grpcL)
+	})
+	s.serve("http", func() error {
+		return s.hSvr.Run(httpL)
+	})
+	if h2L != nil {
+		s.serve("http2", func() error {
+			return s.hSvr.RunH2(h2L)
+		})
+	}
+
+	s.health.SetServing(true)
+	close(s.ready)
+	log.DefaultLog.Info("gorpc server begin to run", slog.String("lnAddr", s.lnAddrs()), slog.String("adminAddr", s.option.AdminAddr), slog.Int("pid", s.pid))
 
+	err = mux.Serve()
+	// https://github.com/soheilhy/cmux/issues/39
+	if err != nil && strings.Contains(err.Error(), "use of closed network connection") {
+		err = nil
+	}
+
+	s.errMux.Lock()
+	defer s.errMux.Unlock()
+	return errors.Join(append(s.errs, err)...)
+}
+
+// serve runs a sub server. If it fails, the whole server is stopped
+// and Run returns the error.
+func (s *Server) serve(name string, run func() error) {
 	go func() {
-		grpcL := s.mux.MatchWithWriters(
-			cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc"))
-		_ = s.gSvr.Run(grpcL)
+		err := run()
+		if err == nil || netutil.IsClosed(err) {
+			return
+		}
+
+		log.DefaultLog.Warn("gorpc sub server failed", slog.String("name", name), slog.Any("err", err))
+		s.errMux.Lock()
+		s.errs = append(s.errs, fmt.Errorf("%s server: %w", name, err))
+		s.errMux.Unlock()
+		_ = s.Stop()
 	}()
+}
 
-	go func() {
-		httpL := s.mux.Match(cmux.HTTP1Fast())
-		_ = s.hSvr.Run(httpL)
+// Ready is closed once Run is serving. It is never closed if Run fails to start,
+// or if the server is stopped before.
+func (s *Server) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Addr returns the address of Addr, or of the first of UnixAddrs if Addr is
+// empty, e.g. to find the port chosen for ":0". It is nil until Ready is closed.
+func (s *Server) Addr() net.Addr {
+	select {
+	case <-s.ready:
+		return s.lns[0].Addr()
+	default:
+		return nil
+	}
+}
+
+// prepare locks the pid file, runs BeforeRun, opens the listeners and writes
+// the runtime files. Everything done so far is undone if a step fails.
+func (s *Server) prepare() (ln net.Listener, err error) {
+	dir := s.option.RuntimeDir
+	if dir == "" {
+		dir = "."
+	}
+	appName := filepath.Base(os.Args[0])
+	pidName := s.option.PidFile
+	if pidName == "" {
+		pidName = fmt.Sprintf("%s.pid", appName)
+	}
+	netName := s.option.NetFile
+	if netName == "" {
+		netName = fmt.Sprintf("%s.net", appName)
+	}
+
+	pidFile := filepath.Join(dir, pidName)
+	inherited := upgrade.Inherited()
+	if !inherited {
+		if s.pidFile, err = runfile.LockPidFile(pidFile); err != nil {
+			return nil, err
+		}
+	}
+	defer func() {
+		if err != nil {
+			s.closeListeners()
+			_ = s.afterStop()
+			s.removeRuntimeFiles()
+		}
 	}()
 
-	s.pidFile = fmt.Sprintf("%s.pid", filepath.Base(os.Args[0]))
+	if s.option.BeforeRun != nil {
+		if err = s.option.BeforeRun(); err != nil {
+			log.DefaultLog.Warn("run handle failed", slog.Any("err", err))
+			return nil, err
+		}
+	}
+	s.beforeRunDone.Store(true)
+
+	if ln, err = s.listen(inherited); err != nil {
+		return nil, err
+	}
+
+	if inherited {
+		// the old process hands the pid file over once it knows we are ready
+		if err = upgrade.NotifyReady(); err != nil {
+			return nil, err
+		}
+		if s.pidFile, err = runfile.LockPidFileWait(pidFile, upgradeTimeout); err != nil {
+			return nil, err
+		}
+	}
+
 	s.pid = os.Getpid()
-	_ = os.WriteFile(s.pidFile, []byte(strconv.Itoa(s.pid)), 0o666)
+	if err = s.pidFile.Write(s.pid); err != nil {
+		return nil, err
+	}
+
+	netFile := filepath.Join(dir, netName)
+	if err = runfile.WriteFile(netFile, []byte(s.lnAddrs())); err != nil {
+		return nil, err
+	}
+	s.netFile = netFile
 
-	s.netFile = fmt.Sprintf("%s.net", filepath.Base(os.Args[0]))
-	_ = os.WriteFile(s.netFile, []byte(ln.Addr().String()), 0o666)
+	return ln, nil
+}
+
+var errInheritedListeners = errors.New("inherited listeners do not match the addresses")
 
-	log.DefaultLog.Info("gorpc server begin to run", slog.Any("lnAddr", ln.Addr()), slog.Int("pid", s.pid))
+// listen opens the tcp listener of Addr, the unix listeners of UnixAddrs and the
+// admin listener, or takes them over from the old process after an upgrade.
+// All of them except the admin one are merged into one listener for cmux.
+func (s *Server) listen(inherited bool) (net.Listener, error) {
+	var fds []net.Listener
+	if inherited {
+		var err error
+		if fds, err = upgrade.Listeners(); err != nil {
+			return nil, err
+		}
+	}
+	defer func() {
+		for _, ln := range fds {
+			_ = ln.Close()
+		}
+	}()
 
-	if err = s.mux.Serve(); err != nil {
-		// https://github.com/soheilhy/cmux/issues/39
-		if strings.Contains(err.Error(), "use of closed network connection") {
-			return nil
+	open := func(network string, addr string) (net.Listener, error) {
+		if !inherited {
+			if network == netutil.UnixScheme {
+				return netutil.ListenUnix(addr, s.option.UnixSockMode)
+			}
+			return net.Listen(network, addr)
 		}
+		if len(fds) == 0 {
+			return nil, errInheritedListeners
+		}
+		ln := fds[0]
+		fds = fds[1:]
+		return ln, nil
+	}
 
-		return err
+	withTCP := s.option.Addr != "" || len(s.option.UnixAddrs) == 0
+	if withTCP {
+		ln, err := open("tcp", s.option.Addr)
+		if err != nil {
+			return nil, err
+		}
+		s.lns = append(s.lns, ln)
 	}
 
-	return nil
+	for _, addr := range s.option.UnixAddrs {
+		ln, err := open(netutil.UnixScheme, addr)
+		if err != nil {
+			return nil, err
+		}
+		s.lns = append(s.lns, ln)
+	}
+
+	if s.option.EnableInnerService && s.option.AdminAddr != "" {
+		ln, err := open("tcp", s.option.AdminAddr)
+		if err != nil {
+			return nil, err
+		}
+		s.adminLn = ln
+	}
+
+	if len(fds) > 0 {
+		return nil, errInheritedListeners
+	}
+
+	lns := append([]net.Listener{}, s.lns...)
+	if withTCP && s.option.TLS != nil {
+		cfg, err := cert.ServerConfig(s.option.TLS)
+		if err != nil {
+			return nil, err
+		}
+		lns[0] = tls.NewListener(lns[0], cfg)
+	}
+
+	return netutil.Multi(lns...), nil
+}
+
+func (s *Server) lnAddrs() string {
+	addrs := make([]string, 0, len(s.lns))
+	for _, ln := range s.lns {
+		addrs = append(addrs, ln.Addr().String())
+	}
+	return strings.Join(addrs, "\n")
 }
 
+// Stop closes the listeners and the connections at once, which cancels the
+// processing requests. AfterStop is called once they have returned, or
+// ShutdownTimeout has elapsed. If Run has not started serving yet, it
+// returns without serving instead.
 func (s *Server) Stop() error {
+	var err error
 	s.stopOnce.Do(func() {
-		log.DefaultLog.Info("gorpc server stopped", slog.Int("pid", s.pid))
-		if s.pidFile != "" {
-			_ = os.Remove(s.pidFile)
+		s.health.SetServing(false)
+		mux := s.stop()
+		if mux == nil {
+			return
 		}
-		if s.netFile != "" {
-			_ = os.Remove(s.netFile)
+		mux.Close()
+		if s.adminLn != nil {
+			_ = s.adminLn.Close()
 		}
-		if s.mux != nil {
-			s.mux.Close()
+
+		c, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout())
+		defer cancel()
+		err = errors.Join(s.hSvr.Close(c), s.gSvr.Stop(c), s.afterStop())
+		s.removeRuntimeFiles()
+		log.DefaultLog.Info("gorpc server stopped", slog.Int("pid", s.pid), slog.Any("err", err))
+	})
+	return err
+}
+
+// Shutdown gracefully stops the server without interrupting active requests.
+// It turns the readiness NOT_SERVING and waits for DrainDelay, then stops
+// accepting new connections, drains the http and grpc servers, sends
+// a going away frame to every websocket session and then waits for the
+// processing requests to finish. If ctx is done before all of that completes,
+// the remaining connections are closed and the ctx error is returned.
+func (s *Server) Shutdown(c context.Context) error {
+	var err error
+	s.stopOnce.Do(func() {
+		s.health.SetServing(false)
+		mux := s.stop()
+		if mux == nil {
+			return
 		}
+		log.DefaultLog.Info("gorpc server begin to shutdown", slog.Int("pid", s.pid))
+		s.drainDelay(c)
+		mux.Close()
+
+		var wg sync.WaitGroup
+		var hErr, gErr error
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			hErr = s.hSvr.Shutdown(c)
+		}()
+		go func() {
+			defer wg.Done()
+			gErr = s.gSvr.Shutdown(c)
+		}()
+		wg.Wait()
+
+		err = errors.Join(hErr, gErr, s.afterStop())
+		s.removeRuntimeFiles()
+		log.DefaultLog.Info("gorpc server stopped", slog.Int("pid", s.pid), slog.Any("err", err))
 	})
-	return nil
+	return err
+}
+
+// start makes mux seen by Stop and Shutdown, unless one of them has been called.
+func (s *Server) start(mux cmux.CMux) bool {
+	s.runMux.Lock()
+	defer s.runMux.Unlock()
+
+	if s.stopped {
+		return false
+	}
+	s.mux = mux
+	return true
+}
+
+// stop marks the server stopped. It returns the mux of Run, or nil if Run has
+// not started serving, which then undoes prepare and returns by itself.
+func (s *Server) stop() cmux.CMux {
+	s.runMux.Lock()
+	defer s.runMux.Unlock()
+
+	s.stopped = true
+	return s.mux
+}
+
+// drainDelay waits for DrainDelay unless c is done earlier.
+func (s *Server) drainDelay(c context.Context) {
+	if s.option.DrainDelay <= 0 {
+		return
+	}
+	log.DefaultLog.Info("gorpc server is draining", slog.Duration("delay", s.option.DrainDelay))
+
+	t := time.NewTimer(s.option.DrainDelay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-c.Done():
+	}
+}
+
+func (s *Server) afterStop() error {
+	if s.option.AfterStop == nil || !s.beforeRunDone.CompareAndSwap(true, false) {
+		return nil
+	}
+
+	err := s.option.AfterStop()
+	if err != nil {
+		log.DefaultLog.Warn("stop handle failed", slog.Any("err", err))
+	}
+	return err
+}
+
+func (s *Server) closeListeners() {
+	for _, ln := range s.lns {
+		_ = ln.Close()
+	}
+	if s.adminLn != nil {
+		_ = s.adminLn.Close()
+	}
+}
+
+func (s *Server) removeRuntimeFiles() {
+	if s.netFile != "" {
+		_ = os.Remove(s.netFile)
+		s.netFile = ""
+	}
+	if s.pidFile != nil {
+		_ = s.pidFile.Release()
+		s.pidFile = nil
+	}
 }
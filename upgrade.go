@@ -0,0 +1,45 @@
+package gorpc
+
+import (
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/BabySid/gorpc/internal/log"
+	"github.com/BabySid/gorpc/internal/upgrade"
+)
+
+const upgradeTimeout = 30 * time.Second
+
+// Upgrade starts a new process of the binary at os.Args[0] with the same
+// arguments and hands the listening sockets over to it, so no connection is
+// refused during a restart. Once the new process is ready it owns the pid and
+// net files, and this server should be drained by Shutdown. It is only
+// supported on linux, where RunUntilSignal does all of that on SIGUSR2.
+func (s *Server) Upgrade() error {
+	lns := append([]net.Listener{}, s.lns...)
+	if s.adminLn != nil {
+		lns = append(lns, s.adminLn)
+	}
+
+	pid, err := upgrade.Start(lns, upgradeTimeout)
+	if err != nil {
+		log.DefaultLog.Warn("gorpc server upgrade failed", slog.Any("err", err))
+		return err
+	}
+
+	// the socket files belong to the new process now
+	for _, ln := range s.lns {
+		if ul, ok := ln.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+	if s.pidFile != nil {
+		_ = s.pidFile.Close()
+		s.pidFile = nil
+	}
+	s.netFile = ""
+
+	log.DefaultLog.Info("gorpc server upgraded", slog.Int("pid", s.pid), slog.Int("newPid", pid))
+	return nil
+}